@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"komodo-op/internal/config"
+)
+
+// runConfig implements the "config" subcommand. Only "config validate" exists,
+// loading the configuration and reporting every problem found at once.
+// Returns the process exit code.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "Usage: komodo-op config validate [-config path]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config validate", flag.ExitOnError)
+	configFile := configFlag(flags)
+	flags.Parse(args[1:])
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		problems := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			problems = joined.Unwrap()
+		}
+		fmt.Fprintf(os.Stderr, "Configuration is invalid, %d problem(s) found:\n", len(problems))
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  - %v\n", problem)
+		}
+		return 1
+	}
+
+	source := "the environment"
+	if cfg.ConfigFile != "" {
+		source += " and " + cfg.ConfigFile
+	}
+	fmt.Printf("Configuration from %s is valid: %d vault(s), %d target(s), %d webhook(s).\n",
+		source, len(cfg.OpVaults), len(cfg.KomodoTargets), len(cfg.Webhooks))
+	return 0
+}
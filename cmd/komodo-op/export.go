@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/logging"
+)
+
+// runExport implements the "export" subcommand, rendering the desired state
+// without writing anything to Komodo.
+// Returns the process exit code.
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "komodo-toml", "Output format. Only \"komodo-toml\" (Komodo ResourceSync TOML) is supported.")
+	target := flags.String("target", "", "Name of the Komodo target to export. Defaults to the first target.")
+	includeValues := flags.Bool("include-values", false, "Include plaintext variable values instead of omitting them.")
+	output := flags.String("output", "", "File to write to. Defaults to stdout.")
+	configFile := configFlag(flags)
+	flags.Parse(args)
+
+	if *format != "komodo-toml" {
+		fmt.Fprintf(os.Stderr, "Invalid format '%s', expected \"komodo-toml\".\n", *format)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	logging.SetFormat(cfg.LogFormat)
+	logging.SetLevel(cfg.LogLevel)
+
+	out := os.Stdout
+	if *output != "" {
+		// Exports may contain plaintext values, keep them private to the user
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			logging.Error("Failed to open output file: %v", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := newSynchronizer(cfg).ExportTOML(*target, out, *includeValues); err != nil {
+		logging.Error("Failed to export desired state: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultHealthcheckURL derives the base URL of the local daemon from HTTP_ADDR.
+func defaultHealthcheckURL() string {
+	addr := strings.TrimSpace(os.Getenv("HTTP_ADDR"))
+	if addr == "" {
+		return ""
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	return "http://" + addr
+}
+
+// runHealthcheck implements the "healthcheck" subcommand, querying the daemon's
+// health endpoints. Suitable for a Docker HEALTHCHECK.
+// Returns the process exit code: 0 when healthy, 1 otherwise.
+func runHealthcheck(args []string) int {
+	flags := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	baseURL := flags.String("url", defaultHealthcheckURL(), "Base URL of the daemon's HTTP endpoints. Defaults to the local HTTP_ADDR.")
+	ready := flags.Bool("ready", false, "Query /readyz instead of /healthz.")
+	timeout := flags.Duration("timeout", 10*time.Second, "Timeout of the health request.")
+	flags.Parse(args)
+
+	if *baseURL == "" {
+		fmt.Fprintln(os.Stderr, "No URL to check: set HTTP_ADDR or pass -url.")
+		return 1
+	}
+
+	path := "/healthz"
+	if *ready {
+		path = "/readyz"
+	}
+	url := strings.TrimSuffix(*baseURL, "/") + path
+
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Health check of %s failed: %v\n", url, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "Health check of %s failed with status %s\n", url, resp.Status)
+		return 1
+	}
+	fmt.Printf("%s: %s\n", url, resp.Status)
+	return 0
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/history"
+	"komodo-op/internal/synchronizer"
+)
+
+// runHistory implements the "history" subcommand, listing the stored run reports
+// ("history [flags]") or showing one of them ("history show <run-id>").
+// Returns the process exit code.
+func runHistory(args []string) int {
+	show := len(args) > 0 && args[0] == "show"
+	if show {
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	format := flags.String("format", "table", "Output format: \"table\" or \"json\".")
+	variable := flags.String("variable", "", "Only list runs that changed or deleted this variable.")
+	limit := flags.Int("limit", 20, "Maximum number of runs listed, newest first. 0 lists all.")
+	configFile := configFlag(flags)
+	flags.Parse(args)
+
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Invalid format '%s', expected \"table\" or \"json\".\n", *format)
+		return 2
+	}
+	if show && flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: komodo-op history show [-format table|json] <run-id>")
+		return 2
+	}
+
+	// Only STATE_DIR is needed, the history can be read without the credentials
+	stateDir, err := config.LoadStateDir(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	if stateDir == "" {
+		fmt.Fprintln(os.Stderr, "STATE_DIR is not set, no run history is kept.")
+		return 1
+	}
+	store := history.NewStore(stateDir, 0) // Only read, the size only matters when appending
+
+	if show {
+		report, err := store.Find(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read run history: %v\n", err)
+			return 1
+		}
+		if report == nil {
+			fmt.Fprintf(os.Stderr, "Run '%s' not found in %s.\n", flags.Arg(0), store.Path())
+			return 1
+		}
+		if *format == "json" {
+			return printJSON(report)
+		}
+		printRunReport(report)
+		return 0
+	}
+
+	reports, err := store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read run history: %v\n", err)
+		return 1
+	}
+	var listed []*synchronizer.Report
+	for i := len(reports) - 1; i >= 0; i-- {
+		if *limit > 0 && len(listed) == *limit {
+			break
+		}
+		if *variable == "" || touchesVariable(reports[i], *variable) {
+			listed = append(listed, reports[i])
+		}
+	}
+
+	if *format == "json" {
+		return printJSON(listed)
+	}
+	printHistoryTable(listed)
+	return 0
+}
+
+// printJSON writes v to stdout as indented JSON. Returns the process exit code.
+func printJSON(v interface{}) int {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode report: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// touchesVariable reports whether a run changed or deleted a variable on any target.
+func touchesVariable(report *synchronizer.Report, name string) bool {
+	for _, changed := range changedVariables(report) {
+		if strings.TrimPrefix(changed, "-") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// changedVariables returns the variables changed by a run across all targets,
+// followed by those deleted prefixed with "-".
+func changedVariables(report *synchronizer.Report) []string {
+	var names []string
+	for _, target := range report.Targets {
+		names = append(names, target.ChangedVariables...)
+		for _, name := range target.DeletedVariables {
+			names = append(names, "-"+name)
+		}
+	}
+	return names
+}
+
+// runTrigger returns what started a run, "-" for runs recorded before triggers were.
+func runTrigger(report *synchronizer.Report) string {
+	if report.Trigger == "" {
+		return "-"
+	}
+	return report.Trigger
+}
+
+// printHistoryTable writes a list of runs to stdout as an aligned table.
+func printHistoryTable(reports []*synchronizer.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN ID\tSTARTED\tTRIGGER\tDURATION\tOUTCOME\tCHANGES\tERRORS\tVARIABLES")
+	for _, report := range reports {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			report.RunID,
+			report.StartedAt.Local().Format(time.DateTime),
+			runTrigger(report),
+			report.Duration().Round(time.Millisecond),
+			report.Outcome(),
+			report.Changes(),
+			report.Errors,
+			strings.Join(changedVariables(report), ", "))
+	}
+	w.Flush()
+}
+
+// printRunReport writes the details of one run to stdout.
+func printRunReport(report *synchronizer.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Run ID:\t%s\n", report.RunID)
+	if report.TraceID != "" {
+		fmt.Fprintf(w, "Trace ID:\t%s\n", report.TraceID)
+	}
+	fmt.Fprintf(w, "Trigger:\t%s\n", runTrigger(report))
+	if report.Partial {
+		fmt.Fprintf(w, "Partial run of:\t%s\n", strings.Join(report.Items, ", "))
+	}
+	if report.Frozen {
+		fmt.Fprintln(w, "Freeze window:\tdeletions deferred")
+	}
+	fmt.Fprintf(w, "Started:\t%s\n", report.StartedAt.Local().Format(time.DateTime))
+	fmt.Fprintf(w, "Finished:\t%s\n", report.FinishedAt.Local().Format(time.DateTime))
+	fmt.Fprintf(w, "Duration:\t%s\n", report.Duration().Round(time.Millisecond))
+	fmt.Fprintf(w, "Outcome:\t%s (%d errors)\n", report.Outcome(), report.Errors)
+
+	for _, target := range report.Targets {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Target '%s'\n", target.Target)
+		fmt.Fprintf(w, "  Created / Updated / Deleted:\t%d / %d / %d\n", target.Created, target.Updated, target.Deleted)
+		fmt.Fprintf(w, "  Corrected / Unchanged / Failed:\t%d / %d / %d\n", target.Corrected, target.Unchanged, target.Failed)
+		if len(target.ChangedVariables) > 0 {
+			fmt.Fprintf(w, "  Changed variables:\t%s\n", strings.Join(target.ChangedVariables, ", "))
+		}
+		if len(target.DeletedVariables) > 0 {
+			fmt.Fprintf(w, "  Deleted variables:\t%s\n", strings.Join(target.DeletedVariables, ", "))
+		}
+		if target.DeletionsBlocked > 0 {
+			fmt.Fprintf(w, "  Deletions blocked by MAX_DELETIONS:\t%d\n", target.DeletionsBlocked)
+		}
+		if target.DeletionsDeferred > 0 {
+			fmt.Fprintf(w, "  Deletions deferred by freeze:\t%d\n", target.DeletionsDeferred)
+		}
+		if target.ExecutionsDeferred > 0 {
+			fmt.Fprintf(w, "  Executions deferred by freeze:\t%d\n", target.ExecutionsDeferred)
+		}
+		if len(target.EnvironmentsUpdated) > 0 {
+			fmt.Fprintf(w, "  Environments updated:\t%s\n", strings.Join(target.EnvironmentsUpdated, ", "))
+		}
+		fmt.Fprintf(w, "  Accounts changed / deleted:\t%d / %d\n", target.AccountsChanged, target.AccountsDeleted)
+		fmt.Fprintf(w, "  Errors:\t%d\n", target.Errors)
+	}
+	w.Flush()
+}
@@ -2,112 +2,428 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"komodo-op/internal/audit"
 	"komodo-op/internal/config"
+	"komodo-op/internal/health"
 	"komodo-op/internal/komodoclient"
 	"komodo-op/internal/logging"
 	"komodo-op/internal/opclient"
+	"komodo-op/internal/schedule"
 	"komodo-op/internal/synchronizer"
+	"komodo-op/internal/tracing"
 )
 
 var Version string
 
+// newSynchronizer initializes the API clients and the synchronizer for a configuration.
+func newSynchronizer(cfg *config.Config) *synchronizer.Synchronizer {
+	// Credentials must never show up in logs, whatever the level
+	logging.RegisterSecret(cfg.OpServiceAccountToken)
+	for _, target := range cfg.KomodoTargets {
+		logging.RegisterSecret(target.APISecret)
+	}
+	for _, value := range cfg.TracingHeaders {
+		logging.RegisterSecret(value)
+	}
+	logging.RegisterSecret(cfg.AuditFingerprintKey, cfg.ControlToken)
+	for _, webhook := range cfg.Webhooks {
+		logging.RegisterSecret(webhook.URL) // Webhook URLs usually embed a token
+	}
+
+	httpClient := &http.Client{Timeout: 60 * time.Second}
+	opClient := opclient.NewClient(httpClient, cfg)
+	komodoClients := make([]*komodoclient.Client, 0, len(cfg.KomodoTargets))
+	for _, target := range cfg.KomodoTargets {
+		komodoClients = append(komodoClients, komodoclient.NewClient(httpClient, target))
+	}
+	return synchronizer.New(opClient, komodoClients, cfg)
+}
+
+// effectiveSchedule returns the sync schedule of daemon mode: the -interval
+// flag, then SYNC_SCHEDULE, then SYNC_INTERVAL.
+func effectiveSchedule(cfg *config.Config, intervalFlag string) (schedule.Schedule, error) {
+	if intervalFlag == "" && cfg.SyncSchedule != nil {
+		return cfg.SyncSchedule, nil
+	}
+	effectiveIntervalStr := cfg.SyncInterval // Start with env var or default
+	if intervalFlag != "" {
+		effectiveIntervalStr = intervalFlag // Override with flag if provided
+	}
+	duration, err := time.ParseDuration(effectiveIntervalStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sync interval format '%s': %w", effectiveIntervalStr, err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("sync interval must be positive")
+	}
+	return schedule.Every(duration), nil
+}
+
+// configFlag registers the -config flag naming the config file, CONFIG_FILE by default.
+func configFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", os.Getenv("CONFIG_FILE"), "JSON config file read for settings not set in the environment. Defaults to CONFIG_FILE.")
+}
+
+// reloadConfig loads and validates the configuration anew, for a reload.
+func reloadConfig(configFile, intervalFlag string) (*config.Config, schedule.Schedule, error) {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	syncSchedule, err := effectiveSchedule(cfg, intervalFlag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, syncSchedule, nil
+}
+
+// watchedFiles returns the files whose changes reload the daemon: the config
+// file and the files settings were read from, e.g. rotated credentials.
+func watchedFiles(cfg *config.Config) []string {
+	files := append([]string{}, cfg.SecretFiles...)
+	if cfg.ConfigFile != "" {
+		files = append(files, cfg.ConfigFile)
+	}
+	return files
+}
+
+// modTimes returns the modification times of files, zero for those that cannot be read.
+func modTimes(files []string) map[string]time.Time {
+	times := make(map[string]time.Time, len(files))
+	for _, file := range files {
+		var modTime time.Time
+		if info, err := os.Stat(file); err == nil {
+			modTime = info.ModTime()
+		}
+		times[file] = modTime
+	}
+	return times
+}
+
+// readinessAge returns how old the last successful run may be for the daemon
+// to be ready. Runs may lag by the longest gap of the schedule, e.g. over a
+// night without runs.
+func readinessAge(cfg *config.Config, syncSchedule schedule.Schedule) time.Duration {
+	return time.Duration(cfg.ReadinessIntervals) * schedule.LongestGap(syncSchedule, time.Now(), 8*24*time.Hour)
+}
+
+// heartbeatInterval is how often the daemon loop records a heartbeat while waiting.
+const heartbeatInterval = 30 * time.Second
+
+// livenessAge returns how long the daemon loop may go without a heartbeat before
+// it counts as stuck. Runs block the loop, so it is one sync interval, or the
+// longest gap of the schedule, and at least 5 minutes.
+func livenessAge(syncSchedule schedule.Schedule) time.Duration {
+	age := schedule.LongestGap(syncSchedule, time.Now(), 8*24*time.Hour)
+	if age < 5*time.Minute {
+		age = 5 * time.Minute
+	}
+	return age
+}
+
+// appliedLogging holds the log format and level last set by configureLogging.
+var appliedLogging *[2]string
+
+// configureLogging sets the log format and level of a configuration, unless
+// they are already set: the daemon applies its configuration again once the
+// runner is created, and on reloads that leave them as they are, which would
+// log the level again each time. Returns whether anything was set.
+func configureLogging(cfg *config.Config) bool {
+	settings := [2]string{cfg.LogFormat, cfg.LogLevel}
+	if appliedLogging != nil && *appliedLogging == settings {
+		return false
+	}
+	appliedLogging = &settings
+	logging.SetFormat(cfg.LogFormat)
+	logging.SetLevel(cfg.LogLevel)
+	return true
+}
+
+// configure applies the process-wide settings of a configuration: logging,
+// tracing and the audit log.
+func configure(cfg *config.Config) {
+	configureLogging(cfg)
+	tracing.Configure(cfg.TracingEndpoint, cfg.TracingServiceName, cfg.TracingHeaders)
+	if err := audit.Configure(cfg.AuditLogPath, int64(cfg.AuditLogMaxSizeMB)<<20, cfg.AuditLogMaxFiles, cfg.AuditFingerprintKey, cfg.StateDir); err != nil {
+		logging.Error("Audit log disabled: %v", err)
+	}
+}
+
+// traceFlushTimeout bounds how long exiting waits for queued traces to be exported.
+const traceFlushTimeout = 5 * time.Second
+
+// exit exports the traces still queued and exits with code.
+func exit(code int) {
+	tracing.Shutdown(traceFlushTimeout)
+	os.Exit(code)
+}
+
+// logConfig logs the loaded configuration, without credentials.
+func logConfig(cfg *config.Config, syncSchedule schedule.Schedule) {
+	logging.Info("Configuration loaded:")
+	if cfg.ConfigFile != "" {
+		logging.Info("  Config file: %s", cfg.ConfigFile)
+	}
+	logging.Info("  OP_CONNECT_HOST: %s", cfg.OpConnectHost)
+	logging.Info("  OP_VAULT (UUID): %s", strings.Join(cfg.OpVaults, ", "))
+	for _, target := range cfg.KomodoTargets {
+		logging.Info("  KOMODO target '%s': %s", target.Name, target.Host)
+	}
+	logging.Info("  Sync schedule: %s (effective)", syncSchedule)
+	for _, window := range cfg.FreezeWindows {
+		logging.Info("  Freeze window: %s (deferring %s)", window, cfg.FreezeMode)
+	}
+	logging.Info("  REDEPLOY_MODE: %s", cfg.RedeployMode)
+	for _, webhook := range cfg.Webhooks {
+		logging.Info("  Webhook (%s): %s", webhook.Format, strings.Join(webhook.Events, ", "))
+	}
+	if cfg.TracingEndpoint != "" {
+		logging.Info("  Tracing: exporting to %s as '%s'", cfg.TracingEndpoint, cfg.TracingServiceName)
+	}
+	if cfg.StateDir != "" {
+		logging.Info("  STATE_DIR: %s (keeping %d runs)", cfg.StateDir, cfg.HistorySize)
+	}
+	if cfg.AuditLogPath != "" {
+		logging.Info("  AUDIT_LOG_PATH: %s (rotated at %d MB, %d files kept)", cfg.AuditLogPath, cfg.AuditLogMaxSizeMB, cfg.AuditLogMaxFiles)
+	}
+}
+
+// logRun logs the outcome of a daemon run, nil if it was deferred by a freeze window.
+func logRun(name string, report *synchronizer.Report) {
+	switch {
+	case report == nil:
+		logging.Info("%s deferred by freeze window.", name)
+	case report.Errors > 0:
+		logging.Error("%s completed with %d errors.", name, report.Errors)
+	default:
+		logging.Info("%s completed successfully.", name)
+	}
+}
+
 func main() {
+	// --- Subcommands ---
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "refs":
+			os.Exit(runRefs(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "healthcheck":
+			os.Exit(runHealthcheck(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		}
+	}
+
 	// --- CLI Flags ---
 	daemonMode := flag.Bool("daemon", false, "Run the application in daemon mode, syncing periodically.")
-	intervalFlag := flag.String("interval", "", "Sync interval for daemon mode (e.g., \"30s\", \"5m\", \"1h\"). Overrides SYNC_INTERVAL env var.")
+	intervalFlag := flag.String("interval", "", "Sync interval for daemon mode (e.g., \"30s\", \"5m\", \"1h\"). Overrides SYNC_SCHEDULE and SYNC_INTERVAL env vars.")
+	configFile := configFlag(flag.CommandLine)
 	flag.Parse()
 
 	// --- Configuration & Logging ---
-	cfg, err := config.LoadConfig()
+	cfg, err := config.LoadConfig(*configFile)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
-	logging.SetLevel(cfg.LogLevel)
+	configureLogging(cfg) // Before anything is logged, configure applies the rest with the runner
 
-	// Determine the effective sync interval
-	effectiveIntervalStr := cfg.SyncInterval // Start with env var or default
-	if *intervalFlag != "" {
-		effectiveIntervalStr = *intervalFlag // Override with flag if provided
+	syncSchedule, err := effectiveSchedule(cfg, *intervalFlag)
+	if err != nil {
+		logging.Error("%v", err)
+		os.Exit(1)
 	}
-
-	logging.Info("Configuration loaded:")
-	logging.Info("  OP_CONNECT_HOST: %s", cfg.OpConnectHost)
-	logging.Info("  OP_VAULT (UUID): %s", cfg.OpVaultUUID)
-	logging.Info("  KOMODO_HOST: %s", cfg.KomodoHost)
-	logging.Info("  SYNC_INTERVAL: %s (effective)", effectiveIntervalStr)
+	logConfig(cfg, syncSchedule)
 
 	// --- Initialize Clients ---
-	httpClient := &http.Client{Timeout: 60 * time.Second}
-	opClient := opclient.NewClient(httpClient, cfg)
-	komodoClient := komodoclient.NewClient(httpClient, cfg)
-	sync := synchronizer.New(opClient, komodoClient, cfg)
+	runner := newRunner(cfg, readinessAge(cfg, syncSchedule))
 
 	// --- Execution Mode ---
 	if *daemonMode {
 		// Daemon Mode
-		duration, err := time.ParseDuration(effectiveIntervalStr)
-		if err != nil {
-			logging.Error("Invalid sync interval format '%s': %v", effectiveIntervalStr, err)
-			os.Exit(1)
-		}
+		logging.Info("Starting daemon mode with sync schedule: %s", syncSchedule)
 
-		if duration <= 0 {
-			logging.Error("Sync interval must be positive.")
-			os.Exit(1)
+		if cfg.ControlToken != "" && cfg.HTTPAddr == "" {
+			logging.Warn("CONTROL_API_TOKEN is set but HTTP_ADDR is not, the control API is disabled.")
+		}
+		if cfg.HTTPAddr != "" {
+			go serveHTTP(cfg.HTTPAddr, runner)
 		}
+		health.SetLoopRunning(true)
+		aliveAge := livenessAge(syncSchedule)
+		health.Heartbeat(aliveAge)
+		heartbeatTicker := time.NewTicker(heartbeatInterval)
+		defer heartbeatTicker.Stop()
 
-		logging.Info("Starting daemon mode with sync interval: %v", duration)
+		// Polls for changed items between full runs, disabled by a nil channel
+		var pollTicker *time.Ticker
+		var pollChan <-chan time.Time
+		setPolling := func(interval time.Duration) {
+			if pollTicker != nil {
+				pollTicker.Stop()
+				pollTicker, pollChan = nil, nil
+			}
+			if interval > 0 {
+				logging.Info("Polling 1Password for changed items every %v", interval)
+				pollTicker = time.NewTicker(interval)
+				pollChan = pollTicker.C
+			}
+		}
+		setPolling(cfg.ChangePollInterval)
+		defer setPolling(0)
 
-		ticker := time.NewTicker(duration)
-		defer ticker.Stop()
+		// Watches the config file and the files settings were read from for
+		// changes, disabled by a nil channel
+		var watchTicker *time.Ticker
+		var watchChan <-chan time.Time
+		var watched map[string]time.Time
+		setWatch := func(cfg *config.Config) {
+			if watchTicker != nil {
+				watchTicker.Stop()
+				watchTicker, watchChan = nil, nil
+			}
+			files := watchedFiles(cfg)
+			watched = modTimes(files)
+			if len(files) > 0 && cfg.ConfigWatchInterval > 0 {
+				watchTicker = time.NewTicker(cfg.ConfigWatchInterval)
+				watchChan = watchTicker.C
+			}
+		}
+		setWatch(cfg)
+		defer func() {
+			if watchTicker != nil {
+				watchTicker.Stop()
+			}
+		}()
 
 		stopChan := make(chan os.Signal, 1)
 		signal.Notify(stopChan, syscall.SIGINT, syscall.SIGTERM)
+		reloadChan := make(chan os.Signal, 1)
+		signal.Notify(reloadChan, syscall.SIGHUP)
+
+		// Swaps in a new configuration, keeping the current one if it is invalid
+		reload := func() {
+			newCfg, newSchedule, err := reloadConfig(*configFile, *intervalFlag)
+			if err != nil {
+				logging.Error("Rejected new configuration, keeping the current one: %v", err)
+				return
+			}
+			if newCfg.HTTPAddr != cfg.HTTPAddr {
+				logging.Warn("HTTP_ADDR changed, the new address takes effect after a restart.")
+			}
+			logConfig(newCfg, newSchedule)
+			runner.reload(newCfg, readinessAge(newCfg, newSchedule))
+			if newCfg.ChangePollInterval != cfg.ChangePollInterval {
+				setPolling(newCfg.ChangePollInterval)
+			}
+			setWatch(newCfg)
+			cfg, syncSchedule = newCfg, newSchedule
+			aliveAge = livenessAge(syncSchedule)
+			logging.Info("Configuration reloaded.")
+		}
 
 		// Run first sync immediately
 		logging.Info("Performing initial sync...")
-		initialErrors := sync.Run()
-		if initialErrors > 0 {
-			logging.Error("Initial sync completed with %d errors.", initialErrors)
-			// Decide if we should exit or continue? For now, continue.
-		} else {
-			logging.Info("Initial sync completed successfully.")
-		}
+		logRun("Initial sync", runner.run(synchronizer.TriggerStartup, nil))
 
-		// Loop, syncing on each tick or exiting on signal
+		// Loop, syncing at each scheduled time, or at the end of a freeze window
+		// that deferred writes, or exiting on signal or after too many failed runs
+		last := time.Now()
+		scheduled := syncSchedule.Next(last)
+		var announced time.Time
 		for {
+			health.Heartbeat(aliveAge)
+			if exhausted, failures := runner.exhausted(); exhausted {
+				logging.Error("%d consecutive runs failed, reaching FAILURE_EXIT_AFTER. Exiting.", failures)
+				health.SetLoopRunning(false)
+				exit(1)
+			}
+
+			wake, catchUp := runner.nextRun(last, scheduled), false
+			if end := runner.catchUpAt(); !end.IsZero() && end.Before(wake) && !runner.backingOff() {
+				wake, catchUp = end, true
+			}
+			if !wake.Equal(announced) {
+				if wake.After(scheduled) {
+					logging.Warn("Backing off after consecutive failed runs, next sync at %s", wake.Format(time.RFC3339))
+				} else {
+					logging.Info("Next sync scheduled at %s", wake.Format(time.RFC3339))
+				}
+				announced = wake
+			}
+			timer := time.NewTimer(time.Until(wake))
+
 			select {
-			case <-ticker.C:
-				logging.Info("Periodic sync triggered...")
-				runErrors := sync.Run()
-				if runErrors > 0 {
-					logging.Error("Periodic sync completed with %d errors.", runErrors)
+			case <-timer.C:
+				if catchUp {
+					logging.Info("Freeze window ended, syncing deferred changes...")
+					logRun("Catch-up sync", runner.run(synchronizer.TriggerCatchUp, nil))
 				} else {
-					logging.Info("Periodic sync completed successfully.")
+					logging.Info("Periodic sync triggered...")
+					logRun("Periodic sync", runner.run(synchronizer.TriggerSchedule, nil))
+					last = time.Now()
+					scheduled = syncSchedule.Next(last)
+				}
+			case <-heartbeatTicker.C:
+				// Wakes the loop for its heartbeat while no run is due
+			case <-pollChan:
+				if runner.backingOff() {
+					break // Polls would fail alike, e.g. on revoked credentials
+				}
+				if report := runner.runChanged(); report != nil {
+					logRun("Sync of changed items", report)
+				}
+			case <-reloadChan:
+				logging.Info("Received SIGHUP. Reloading configuration...")
+				reload()
+				scheduled = syncSchedule.Next(last)
+			case <-watchChan:
+				current := modTimes(watchedFiles(cfg))
+				for file, modTime := range current {
+					if !modTime.Equal(watched[file]) {
+						logging.Info("%s changed. Reloading configuration...", file)
+						watched = current
+						reload()
+						scheduled = syncSchedule.Next(last)
+						break
+					}
 				}
 			case <-stopChan:
+				timer.Stop()
 				logging.Info("Received shutdown signal. Exiting daemon mode...")
+				health.SetLoopRunning(false)
+				tracing.Shutdown(traceFlushTimeout)
 				return // Exit main
 			}
+			timer.Stop()
 		}
 
 	} else {
 		// One-off Sync Mode (Default)
 		logging.Info("Starting one-off sync...")
-		totalErrors := sync.Run()
+		report := runner.run(synchronizer.TriggerOneOff, nil)
+		if report == nil {
+			logging.Info("Synchronization deferred by freeze window.")
+			exit(0)
+		}
+		totalErrors := report.Errors
 		if totalErrors > 0 {
 			logging.Error("Synchronization completed with %d errors.", totalErrors)
-			os.Exit(1)
+			exit(1)
 		} else {
 			logging.Info("Synchronization completed successfully.")
-			os.Exit(0)
+			exit(0)
 		}
 	}
 }
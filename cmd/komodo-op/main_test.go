@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"komodo-op/internal/config"
+)
+
+func TestConfigureLoggingOnce(t *testing.T) {
+	previous := appliedLogging
+	t.Cleanup(func() { appliedLogging = previous })
+	appliedLogging = nil
+
+	cfg := &config.Config{LogFormat: "text", LogLevel: "INFO"}
+	if !configureLogging(cfg) {
+		t.Fatal("configureLogging() did not set the initial settings")
+	}
+	// Applied again by the runner at startup, and by reloads leaving them as they are
+	if configureLogging(&config.Config{LogFormat: "text", LogLevel: "INFO"}) {
+		t.Error("configureLogging() set unchanged settings again")
+	}
+	if !configureLogging(&config.Config{LogFormat: "text", LogLevel: "DEBUG"}) {
+		t.Error("configureLogging() ignored a changed level")
+	}
+	if !configureLogging(&config.Config{LogFormat: "json", LogLevel: "DEBUG"}) {
+		t.Error("configureLogging() ignored a changed format")
+	}
+	configureLogging(cfg)
+}
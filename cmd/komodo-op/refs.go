@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/logging"
+	"komodo-op/internal/synchronizer"
+)
+
+// runRefs implements the "refs" subcommand, reporting dangling and unused variable references.
+// Returns the process exit code.
+func runRefs(args []string) int {
+	flags := flag.NewFlagSet("refs", flag.ExitOnError)
+	format := flags.String("format", "table", "Output format: \"table\" or \"json\".")
+	target := flags.String("target", "", "Name of the Komodo target to check. Defaults to the first target.")
+	configFile := configFlag(flags)
+	flags.Parse(args)
+
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Invalid format '%s', expected \"table\" or \"json\".\n", *format)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	logging.SetFormat(cfg.LogFormat)
+	logging.SetLevel(cfg.LogLevel)
+
+	report, err := newSynchronizer(cfg).CheckReferences(*target)
+	if err != nil {
+		logging.Error("Failed to check variable references: %v", err)
+		return 1
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			logging.Error("Failed to encode report: %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	printRefsTable(report)
+	return 0
+}
+
+// printRefsTable writes a reference report to stdout as aligned tables.
+func printRefsTable(report *synchronizer.ReferenceReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "DANGLING REFERENCES (%d)\n", len(report.Dangling))
+	fmt.Fprintln(w, "VARIABLE\tREFERENCED BY")
+	for _, ref := range report.Dangling {
+		fmt.Fprintf(w, "%s\t%s\n", ref.Variable, strings.Join(ref.Resources, ", "))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "UNUSED MANAGED VARIABLES (%d)\n", len(report.Unused))
+	fmt.Fprintln(w, "VARIABLE")
+	for _, name := range report.Unused {
+		fmt.Fprintln(w, name)
+	}
+
+	w.Flush()
+}
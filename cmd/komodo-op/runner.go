@@ -0,0 +1,209 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/history"
+	"komodo-op/internal/logging"
+	"komodo-op/internal/notify"
+	"komodo-op/internal/schedule"
+	"komodo-op/internal/synchronizer"
+)
+
+// runner serializes the runs of the process, whether periodic or requested
+// through the control API, and publishes their reports. Its configuration is
+// swapped between runs on reload.
+type runner struct {
+	mu           sync.Mutex
+	controlToken string
+	readinessAge time.Duration
+	sync         *synchronizer.Synchronizer
+	notifier     *notify.Notifier
+	history      *history.Store // nil while STATE_DIR is not set
+
+	freezeWindows []schedule.Window
+	freezeMode    string
+	deferredUntil time.Time // End of the freeze window that deferred writes, zero if none
+
+	backoffAfter        int
+	backoffMax          time.Duration
+	exitAfter           int
+	consecutiveFailures int // Runs failed in a row since the last run without errors
+}
+
+// newRunner creates a runner for a configuration. readinessAge is how old the
+// last successful run may be for the daemon to be ready.
+func newRunner(cfg *config.Config, readinessAge time.Duration) *runner {
+	r := &runner{}
+	r.apply(cfg, readinessAge)
+	return r
+}
+
+// reload swaps the configuration, clients and synchronizer once any run in
+// progress has finished. The streak of failed runs carries over.
+func (r *runner) reload(cfg *config.Config, readinessAge time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apply(cfg, readinessAge)
+}
+
+// apply sets up the runner and the process-wide settings for a configuration.
+// Callers hold mu, if shared.
+func (r *runner) apply(cfg *config.Config, readinessAge time.Duration) {
+	configure(cfg)
+	r.controlToken = cfg.ControlToken
+	r.readinessAge = readinessAge
+	next := newSynchronizer(cfg)
+	if r.sync != nil {
+		next.Inherit(r.sync) // Otherwise the first poll would sync every item again
+	}
+	r.sync = next
+	r.notifier = notify.New(cfg) // Stateless, the failure streak is the runner's
+	r.history = nil
+	if cfg.StateDir != "" {
+		r.history = history.NewStore(cfg.StateDir, cfg.HistorySize)
+	}
+	r.freezeWindows = cfg.FreezeWindows
+	r.freezeMode = cfg.FreezeMode
+	r.backoffAfter = cfg.FailureBackoffAfter
+	r.backoffMax = cfg.FailureBackoffMax
+	r.exitAfter = cfg.FailureExitAfter
+}
+
+// settings returns the control API token and the readiness age of the current configuration.
+func (r *runner) settings() (string, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.controlToken, r.readinessAge
+}
+
+// run synchronizes all items, or only the given ones, once any run in progress has finished.
+// The report records trigger as what started the run.
+// Returns nil if the run was deferred by a freeze window.
+func (r *runner) run(trigger string, items []string) *synchronizer.Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.freeze(logging.Warn) {
+		return nil
+	}
+	var report *synchronizer.Report
+	if len(items) > 0 {
+		report = r.sync.RunItems(items)
+	} else {
+		report = r.sync.Run()
+		if !report.Frozen {
+			r.deferredUntil = time.Time{} // Caught up on the deferred writes
+		}
+	}
+	report.Trigger = trigger
+	r.publish(report)
+	return report
+}
+
+// runChanged synchronizes the items changed since they were last fetched.
+// Returns nil if nothing changed.
+func (r *runner) runChanged() *synchronizer.Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.freeze(logging.Debug) {
+		return nil
+	}
+	report := r.sync.RunChanged()
+	if report != nil {
+		report.Trigger = synchronizer.TriggerPoll
+		r.publish(report)
+	}
+	return report
+}
+
+// freeze applies the freeze window active now, if any, to the next run: with
+// FREEZE_MODE "all" the run is deferred and true returned, otherwise only its
+// deletions are, which is logged with logf. The end of the window is kept for
+// a full catch-up run. Callers hold mu.
+func (r *runner) freeze(logf func(format string, args ...interface{})) bool {
+	end, frozen := schedule.ActiveAny(r.freezeWindows, time.Now())
+	r.sync.SetDeletionsFrozen(frozen)
+	if !frozen {
+		return false
+	}
+	r.deferredUntil = end
+	if r.freezeMode == config.FreezeModeAll {
+		logf("Freeze window active until %s, deferring sync.", end.Format(time.RFC3339))
+		return true
+	}
+	logf("Freeze window active until %s, deferring deletions.", end.Format(time.RFC3339))
+	return false
+}
+
+// catchUpAt returns when the freeze window that deferred writes ends, zero if none did.
+func (r *runner) catchUpAt() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deferredUntil
+}
+
+// publish notifies the webhooks of a finished run and records it in the history.
+// Callers hold mu.
+func (r *runner) publish(report *synchronizer.Report) {
+	if report.Errors > 0 {
+		r.consecutiveFailures++
+	} else {
+		r.consecutiveFailures = 0
+	}
+	r.notifier.Notify(report, r.consecutiveFailures)
+	if r.history != nil {
+		if err := r.history.Append(report); err != nil {
+			logging.Error("Failed to record run in history: %v", err)
+		}
+	}
+}
+
+// backingOff reports whether enough runs failed in a row to back off.
+func (r *runner) backingOff() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.backoffAfter > 0 && r.consecutiveFailures >= r.backoffAfter
+}
+
+// nextRun returns when to run next, given the last periodic run and the next
+// scheduled time. While backing off, the time between them doubles with each
+// failed run, up to FAILURE_BACKOFF_MAX; backing off never makes runs more frequent.
+func (r *runner) nextRun(last, scheduled time.Time) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.backoffAfter == 0 || r.consecutiveFailures < r.backoffAfter {
+		return scheduled
+	}
+
+	delay := scheduled.Sub(last)
+	for i := r.backoffAfter; i <= r.consecutiveFailures && delay < r.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > r.backoffMax {
+		delay = r.backoffMax
+	}
+	if next := last.Add(delay); next.After(scheduled) {
+		return next
+	}
+	return scheduled
+}
+
+// exhausted reports whether enough runs failed in a row for the daemon to exit,
+// and how many did.
+func (r *runner) exhausted() (bool, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exitAfter > 0 && r.consecutiveFailures >= r.exitAfter, r.consecutiveFailures
+}
+
+// checkConnectivity checks the dependencies of the current synchronizer.
+func (r *runner) checkConnectivity() map[string]error {
+	r.mu.Lock()
+	current := r.sync
+	r.mu.Unlock()
+	return current.CheckConnectivity()
+}
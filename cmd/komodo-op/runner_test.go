@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRun(t *testing.T) {
+	last := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	hourly := last.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		after     int
+		max       time.Duration
+		failures  int
+		scheduled time.Time
+		want      time.Time
+	}{
+		{"backoff disabled", 0, 6 * time.Hour, 10, hourly, hourly},
+		{"below the threshold", 3, 6 * time.Hour, 2, hourly, hourly},
+		{"at the threshold", 3, 6 * time.Hour, 3, hourly, last.Add(2 * time.Hour)},
+		{"doubling with each failure", 3, 6 * time.Hour, 4, hourly, last.Add(4 * time.Hour)},
+		{"capped at the maximum", 3, 6 * time.Hour, 5, hourly, last.Add(6 * time.Hour)},
+		{"capped long after the threshold", 3, 6 * time.Hour, 1000, hourly, last.Add(6 * time.Hour)},
+		{"short cron interval", 1, time.Hour, 2, last.Add(5 * time.Minute), last.Add(20 * time.Minute)},
+		{"never before the schedule", 1, 30 * time.Minute, 1, hourly, hourly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &runner{backoffAfter: tt.after, backoffMax: tt.max, consecutiveFailures: tt.failures}
+			if got := r.nextRun(last, tt.scheduled); !got.Equal(tt.want) {
+				t.Errorf("nextRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackingOff(t *testing.T) {
+	tests := []struct {
+		after, failures int
+		want            bool
+	}{
+		{0, 5, false},
+		{3, 2, false},
+		{3, 3, true},
+		{3, 4, true},
+	}
+	for _, tt := range tests {
+		r := &runner{backoffAfter: tt.after, consecutiveFailures: tt.failures}
+		if got := r.backingOff(); got != tt.want {
+			t.Errorf("backingOff() after %d of %d failures = %v, want %v", tt.failures, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	tests := []struct {
+		exitAfter, failures int
+		want                bool
+	}{
+		{0, 100, false},
+		{5, 4, false},
+		{5, 5, true},
+		{5, 6, true},
+	}
+	for _, tt := range tests {
+		r := &runner{exitAfter: tt.exitAfter, consecutiveFailures: tt.failures}
+		got, failures := r.exhausted()
+		if got != tt.want || failures != tt.failures {
+			t.Errorf("exhausted() after %d of %d failures = %v, %d, want %v, %d",
+				tt.failures, tt.exitAfter, got, failures, tt.want, tt.failures)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"komodo-op/internal/health"
+	"komodo-op/internal/logging"
+	"komodo-op/internal/metrics"
+	"komodo-op/internal/synchronizer"
+)
+
+// serveHTTP serves the daemon's HTTP endpoints until the process exits.
+// The daemon is ready while its last successful sync is at most the runner's
+// readiness age old. The control API is only served while a control token is set.
+func serveHTTP(addr string, runner *runner) {
+	readinessAge := func() time.Duration {
+		_, age := runner.settings()
+		return age
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", health.ReadinessHandler(readinessAge, runner.checkConnectivity))
+	mux.Handle("/sync", syncHandler(runner))
+
+	logging.Info("Serving HTTP endpoints on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logging.Error("HTTP server on %s stopped: %v", addr, err)
+	}
+}
+
+// syncHandler serves POST /sync while CONTROL_API_TOKEN is set, running a sync as soon as any run in progress
+// has finished and responding with its report. Repeated "item" query parameters
+// (ID or title) restrict the run to these items. Responds 409 if a freeze
+// window with FREEZE_MODE "all" deferred the run.
+func syncHandler(runner *runner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := runner.settings()
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		var items []string
+		for _, item := range r.URL.Query()["item"] {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		if len(items) > 0 {
+			logging.Info("Sync of items %s requested through the control API.", strings.Join(items, ", "))
+		} else {
+			logging.Info("Sync requested through the control API.")
+		}
+
+		report := runner.run(synchronizer.TriggerAPI, items)
+		if report == nil {
+			writeError(w, http.StatusConflict, "sync deferred by freeze window")
+			return
+		}
+		status := http.StatusOK
+		if report.Errors > 0 {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
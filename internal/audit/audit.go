@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operations recorded in the audit log.
+const (
+	OpCreate  = "create"
+	OpUpdate  = "update"
+	OpCorrect = "correct" // Only the description or is_secret flag was rewritten
+	OpDelete  = "delete"
+)
+
+// Record is one line of the audit log. It never contains secret values.
+type Record struct {
+	Timestamp   time.Time `json:"timestamp"`
+	RunID       string    `json:"run_id"`
+	Target      string    `json:"target"`
+	Op          string    `json:"op"`
+	Variable    string    `json:"variable,omitempty"` // Variable, or key of a resource environment
+	Resource    string    `json:"resource,omitempty"` // Resource whose environment was edited, as Type/name
+	Account     string    `json:"account,omitempty"`  // Account created, updated or deleted
+	VaultID     string    `json:"vault_id,omitempty"`
+	ItemID      string    `json:"item_id,omitempty"`
+	FieldID     string    `json:"field_id,omitempty"`
+	ItemVersion int       `json:"item_version,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"` // Of the value written, see Fingerprint
+}
+
+// keyFile is the name of the fingerprint key generated in the state directory.
+const keyFile = "audit-fingerprint.key"
+
+// auditLog appends records to a JSONL file, rotating it by size.
+type auditLog struct {
+	path           string
+	maxBytes       int64
+	maxFiles       int
+	fingerprintKey []byte
+}
+
+var (
+	mu      sync.Mutex
+	current *auditLog // nil while the audit log is disabled
+)
+
+// Configure enables the audit log at path. Once the file would grow past
+// maxBytes it is rotated to path.1, keeping at most maxFiles rotated files.
+// An empty path disables the audit log.
+//
+// Values are fingerprinted with fingerprintKey, or else with a key generated
+// once and kept in stateDir. Without either, or if the generated key cannot be
+// read or written, the audit log stays disabled and an error is returned:
+// unkeyed digests of short values could be brute-forced from the file.
+func Configure(path string, maxBytes int64, maxFiles int, fingerprintKey, stateDir string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	current = nil
+	if path == "" {
+		return nil
+	}
+	if fingerprintKey == "" {
+		if stateDir == "" {
+			return fmt.Errorf("audit log %s needs a fingerprint key, from AUDIT_FINGERPRINT_KEY or generated in STATE_DIR", path)
+		}
+		var err error
+		if fingerprintKey, err = loadOrCreateKey(filepath.Join(stateDir, keyFile)); err != nil {
+			return err
+		}
+	}
+	current = &auditLog{path: path, maxBytes: maxBytes, maxFiles: maxFiles, fingerprintKey: []byte(fingerprintKey)}
+	return nil
+}
+
+// loadOrCreateKey reads the fingerprint key at path, generating a random one
+// first if the file does not exist.
+func loadOrCreateKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if key := strings.TrimSpace(string(data)); key != "" {
+			return key, nil
+		}
+		return "", fmt.Errorf("fingerprint key %s is empty", path)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("failed to read fingerprint key: %w", err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate fingerprint key: %w", err)
+	}
+	key := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create state directory: %w", err)
+	}
+	// O_EXCL, so that a key written concurrently is never replaced
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return loadOrCreateKey(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write fingerprint key: %w", err)
+	}
+	if _, err := file.WriteString(key + "\n"); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write fingerprint key: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to write fingerprint key: %w", err)
+	}
+	return key, nil
+}
+
+// Enabled reports whether records are written.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return current != nil
+}
+
+// Fingerprint returns a short, stable HMAC-SHA256 fingerprint of a value,
+// allowing to tell whether two writes carried the same value without revealing
+// it. Returns an empty string while the audit log is disabled.
+func Fingerprint(value string) string {
+	mu.Lock()
+	var key []byte
+	if current != nil {
+		key = current.fingerprintKey
+	}
+	mu.Unlock()
+
+	if len(key) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// Write appends a record to the audit log. It is a no-op while the audit log is disabled.
+func Write(record Record) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	if err := current.rotateIfNeeded(int64(len(line))); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(current.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", current.path, err)
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit log %s: %w", current.path, err)
+	}
+	return file.Close()
+}
+
+// rotateIfNeeded rotates the file if appending size bytes would exceed maxBytes.
+// Rotated files are numbered from 1 (newest) to maxFiles (oldest). Callers hold mu.
+func (l *auditLog) rotateIfNeeded(size int64) error {
+	if l.maxBytes <= 0 {
+		return nil
+	}
+	info, err := os.Stat(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat audit log %s: %w", l.path, err)
+	}
+	if info.Size() == 0 || info.Size()+size <= l.maxBytes {
+		return nil
+	}
+
+	if l.maxFiles <= 0 {
+		return os.Remove(l.path)
+	}
+	oldest := fmt.Sprintf("%s.%d", l.path, l.maxFiles)
+	if err := os.Remove(oldest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove rotated audit log %s: %w", oldest, err)
+	}
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", l.path, i)
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", l.path, i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log %s: %w", from, err)
+		}
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate audit log %s: %w", l.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func disable(t *testing.T) {
+	t.Cleanup(func() { Configure("", 0, 0, "", "") })
+}
+
+func TestConfigureNeedsKey(t *testing.T) {
+	disable(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := Configure(path, 0, 0, "", ""); err == nil || !strings.Contains(err.Error(), "needs a fingerprint key") {
+		t.Fatalf("Configure() without key nor state directory: error = %v", err)
+	}
+	if Enabled() {
+		t.Error("audit log enabled without fingerprint key")
+	}
+	if got := Fingerprint("secret"); got != "" {
+		t.Errorf("Fingerprint() = %q while disabled, want none", got)
+	}
+}
+
+func TestFingerprintWithKey(t *testing.T) {
+	disable(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := Configure(path, 0, 0, "key-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	first := Fingerprint("secret")
+	if !strings.HasPrefix(first, "hmac-sha256:") || len(first) != len("hmac-sha256:")+32 {
+		t.Errorf("Fingerprint() = %q, want a truncated HMAC-SHA256", first)
+	}
+	if Fingerprint("secret") != first || Fingerprint("other") == first {
+		t.Error("fingerprints are not stable per value")
+	}
+	sum := sha256.Sum256([]byte("secret"))
+	if strings.Contains(first, hex.EncodeToString(sum[:])[:32]) {
+		t.Error("fingerprint is a plain SHA-256 of the value")
+	}
+
+	Configure(path, 0, 0, "key-2", "")
+	if Fingerprint("secret") == first {
+		t.Error("fingerprints of different keys are equal")
+	}
+}
+
+func TestGeneratedKey(t *testing.T) {
+	disable(t)
+	dir := t.TempDir()
+	stateDir := filepath.Join(dir, "state")
+	path := filepath.Join(dir, "audit.jsonl")
+
+	if err := Configure(path, 0, 0, "", stateDir); err != nil {
+		t.Fatal(err)
+	}
+	first := Fingerprint("secret")
+	info, err := os.Stat(filepath.Join(stateDir, keyFile))
+	if err != nil {
+		t.Fatalf("key not persisted: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("key file mode = %v, want 0600", mode)
+	}
+
+	// The key is kept across restarts
+	if err := Configure(path, 0, 0, "", stateDir); err != nil {
+		t.Fatal(err)
+	}
+	if got := Fingerprint("secret"); got != first {
+		t.Errorf("Fingerprint() after restart = %q, want %q", got, first)
+	}
+
+	os.WriteFile(filepath.Join(stateDir, keyFile), []byte("\n"), 0600)
+	if err := Configure(path, 0, 0, "", stateDir); err == nil || Enabled() {
+		t.Errorf("Configure() with an empty key file: error = %v, enabled = %v", err, Enabled())
+	}
+}
+
+func TestWriteRotates(t *testing.T) {
+	disable(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := Configure(path, 200, 2, "key", ""); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := Write(Record{Op: OpUpdate, Variable: "DB__PASSWORD", Fingerprint: Fingerprint("v")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(data) > 200 || !strings.Contains(string(data), `"op":"update"`) {
+			t.Errorf("%s holds %d bytes: %s", name, len(data), data)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more than 2 rotated files kept: %v", err)
+	}
+}
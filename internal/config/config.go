@@ -1,86 +1,363 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"strconv"
 	"strings"
-	// Import time for default duration
+	"time"
+
+	"komodo-op/internal/schedule"
 	// "log" // Temporarily remove direct logging, will be handled in main
 )
 
 // Config holds the application configuration.
 type Config struct {
 	OpConnectHost         string
-	OpVaultUUID           string // User-provided UUID (or name, though we now assume UUID)
+	OpVaultUUID           string   // User-provided UUID (or name, though we now assume UUID); comma-separated for several vaults
+	OpVaults              []string // Vaults synced from, in order; items of later vaults win on name clashes
 	OpServiceAccountToken string
-	KomodoHost            string
-	KomodoAPIKey          string
-	KomodoAPISecret       string
-	LogLevel              string // Keep for initial read by main
-	SyncInterval          string // Interval for daemon mode (e.g., "1h", "30m")
+	KomodoTargets         []KomodoTarget    // Komodo instances synced to, at least one
+	LogLevel              string            // Keep for initial read by main
+	LogFormat             string            // "text" (default) or "json"
+	SyncInterval          string            // Interval for daemon mode (e.g., "1h", "30m")
+	SyncSchedule          schedule.Schedule // Schedule of daemon mode runs, overriding SyncInterval; nil if unset
+	ChangePollInterval    time.Duration     // Interval of the polls syncing changed items in daemon mode, 0 to disable
+	HTTPAddr              string            // Listen address of the daemon's HTTP endpoints (e.g., ":9090"), empty to disable
+	ReadinessIntervals    int               // Sync intervals after which a missing successful sync makes the daemon unready
+	ControlToken          string            // Bearer token of the control API (POST /sync), empty to disable it
+
+	// Config file the settings not set in the environment are read from, empty if none
+	ConfigFile          string
+	ConfigWatchInterval time.Duration // Interval of the checks reloading the daemon when ConfigFile or SecretFiles changed, 0 to disable
+	SecretFiles         []string      // Files settings were read from through NAME_FILE variables, such as credentials
+
+	// Policy deciding which variables are marked as secret in Komodo
+	SecretPolicy        string          // One of SecretPolicyAll, SecretPolicyFieldType
+	NonSecretFieldTypes []string        // 1Password field types synced as plain variables under SecretPolicyFieldType
+	SecretOverrides     map[string]bool // Explicit is_secret per variable name, wins over the policy
+
+	// Recurring periods during which writes are deferred
+	FreezeWindows []schedule.Window
+	FreezeMode    string // One of FreezeModeAll, FreezeModeDeletes
+
+	// Daemon mode policy after consecutive failed runs
+	FailureBackoffAfter int           // Failed runs after which the time between runs doubles with each failure, 0 to disable
+	FailureBackoffMax   time.Duration // Longest time between runs while backing off
+	FailureExitAfter    int           // Failed runs after which the daemon exits non-zero, 0 to disable
+
+	// Orphaned variables deleted per target and run at most, 0 for no limit.
+	// Targets may override it, see KomodoTarget.MaxDeletions.
+	MaxDeletions int
+
+	// Redeploy of resources affected by changed secrets
+	RedeployMode      string   // One of RedeployOff, RedeployDryRun, RedeployDeploy
+	RedeployAllowlist []string // Resources allowed to be redeployed ("name" or "kind:name"); empty allows all
+	RedeployProcedure string   // Procedure run once instead of deploying each affected resource
+
+	// Executions triggered after a sync that changed at least one variable
+	PostSyncProcedure string
+	PostSyncAction    string
+
+	// OpenTelemetry tracing of sync runs, disabled while TracingEndpoint is empty
+	TracingEndpoint    string            // OTLP/HTTP traces endpoint (e.g., "http://otel-collector:4318/v1/traces")
+	TracingServiceName string            // service.name resource attribute
+	TracingHeaders     map[string]string // Headers sent with every export, e.g. for authentication
+
+	// Append-only JSONL audit log of variable changes, disabled while AuditLogPath is empty
+	AuditLogPath        string
+	AuditLogMaxSizeMB   int    // Size after which the audit log is rotated
+	AuditLogMaxFiles    int    // Rotated audit log files kept
+	AuditFingerprintKey string // Key of the HMAC fingerprinting values, generated in StateDir when empty
+
+	// Outbound notifications of run results
+	Webhooks                []Webhook
+	WebhookFailureThreshold int // Consecutive failed runs triggering EventConsecutiveFailures
+
+	// Persistent state, such as the run history, disabled while StateDir is empty
+	StateDir    string
+	HistorySize int // Run reports kept in the history
 
 	// Internal: Populated during load or later steps
-	OpVaultID string // Resolved Vault ID (currently same as OpVaultUUID)
+	OpVaultID string // Resolved Vault ID (currently the first of OpVaults)
 }
 
 // DefaultSyncInterval defines the default sync interval if not set via env var.
 const DefaultSyncInterval = "1h"
 
-// LoadConfig loads configuration from environment variables.
-func LoadConfig() (*Config, error) {
-	syncInterval := os.Getenv("SYNC_INTERVAL")
+// DefaultReadinessIntervals defines the default of READINESS_INTERVALS.
+const DefaultReadinessIntervals = 2
+
+// Supported values for SECRET_POLICY.
+const (
+	SecretPolicyAll       = "all"
+	SecretPolicyFieldType = "field-type"
+)
+
+// DefaultNonSecretFieldTypes lists the field types synced as plain variables under SecretPolicyFieldType.
+const DefaultNonSecretFieldTypes = "STRING,URL,EMAIL"
+
+// DefaultTracingServiceName defines the default of OTEL_SERVICE_NAME.
+const DefaultTracingServiceName = "komodo-op"
+
+// Defaults of AUDIT_LOG_MAX_SIZE_MB and AUDIT_LOG_MAX_FILES.
+const (
+	DefaultAuditLogMaxSizeMB = 10
+	DefaultAuditLogMaxFiles  = 5
+)
+
+// DefaultHistorySize defines the default of HISTORY_SIZE.
+const DefaultHistorySize = 50
+
+// DefaultFailureBackoffMax defines the default of FAILURE_BACKOFF_MAX.
+const DefaultFailureBackoffMax = 6 * time.Hour
+
+// Supported values for FREEZE_MODE.
+const (
+	FreezeModeAll     = "all"     // Runs are deferred altogether, the default
+	FreezeModeDeletes = "deletes" // Only deletions are deferred, creates and updates still run
+)
+
+// Supported values for REDEPLOY_MODE.
+const (
+	RedeployOff    = "off"
+	RedeployDryRun = "dry-run"
+	RedeployDeploy = "deploy"
+)
+
+// splitList splits a comma-separated environment value into its trimmed, non-empty entries.
+func splitList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// parseSecretOverrides parses a comma-separated list of NAME=true|false pairs.
+func parseSecretOverrides(value string) (map[string]bool, error) {
+	overrides := make(map[string]bool)
+	for _, entry := range splitList(value) {
+		name, flag, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("SECRET_OVERRIDES entry %q must have the form NAME=true|false", entry)
+		}
+		isSecret, err := strconv.ParseBool(strings.TrimSpace(flag))
+		if err != nil {
+			return nil, fmt.Errorf("SECRET_OVERRIDES entry %q must have the form NAME=true|false", entry)
+		}
+		overrides[name] = isSecret
+	}
+	return overrides, nil
+}
+
+// tracingEndpoint returns the OTLP/HTTP traces endpoint following the OpenTelemetry
+// conventions: OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is used as-is, while
+// OTEL_EXPORTER_OTLP_ENDPOINT is a base URL the traces path is appended to.
+func (l *loader) tracingEndpoint() string {
+	if endpoint := strings.TrimSpace(l.get("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := strings.TrimSpace(l.get("OTEL_EXPORTER_OTLP_ENDPOINT")); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// parseHeaders parses a comma-separated list of key=value pairs.
+func parseHeaders(name, value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, entry := range splitList(value) {
+		key, headerValue, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s entry must have the form key=value", name)
+		}
+		headers[key] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
+
+// LoadConfig loads configuration from environment variables, falling back to
+// the JSON config file at path for settings not set in the environment. An
+// empty path reads the environment only. Any setting may be read from a file
+// named by NAME_FILE instead, which is read anew on each call. Every problem
+// found is reported at once, joined in the returned error.
+func LoadConfig(path string) (*Config, error) {
+	l := &loader{}
+	if path != "" {
+		file, err := loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		l.file = file
+		l.problems = append(l.problems, file.problems...)
+	}
+
+	syncInterval := l.get("SYNC_INTERVAL")
 	if syncInterval == "" {
 		syncInterval = DefaultSyncInterval
 	}
+	if interval, err := time.ParseDuration(syncInterval); err != nil || interval <= 0 {
+		l.problem("SYNC_INTERVAL must be a positive duration (e.g., \"1h\"), got %q", syncInterval)
+	}
+
+	redeployMode := strings.ToLower(strings.TrimSpace(l.get("REDEPLOY_MODE")))
+	if redeployMode == "" {
+		redeployMode = RedeployOff
+	}
+
+	var syncSchedule schedule.Schedule
+	if value := strings.TrimSpace(l.get("SYNC_SCHEDULE")); value != "" {
+		var err error
+		if syncSchedule, err = schedule.Parse(value); err != nil {
+			l.problem("SYNC_SCHEDULE: %v", err)
+		}
+	}
+	freezeWindows, err := schedule.ParseWindows(l.get("FREEZE_WINDOWS"))
+	if err != nil {
+		l.problem("FREEZE_WINDOWS: %v", err)
+	}
+	freezeMode := strings.ToLower(strings.TrimSpace(l.get("FREEZE_MODE")))
+	if freezeMode == "" {
+		freezeMode = FreezeModeAll
+	}
+
+	secretPolicy := strings.ToLower(strings.TrimSpace(l.get("SECRET_POLICY")))
+	if secretPolicy == "" {
+		secretPolicy = SecretPolicyAll
+	}
+	nonSecretFieldTypes := l.get("NON_SECRET_FIELD_TYPES")
+	if nonSecretFieldTypes == "" {
+		nonSecretFieldTypes = DefaultNonSecretFieldTypes
+	}
+	secretOverrides, err := parseSecretOverrides(l.get("SECRET_OVERRIDES"))
+	if err != nil {
+		l.problems = append(l.problems, err)
+	}
+
+	tracingServiceName := strings.TrimSpace(l.get("OTEL_SERVICE_NAME"))
+	if tracingServiceName == "" {
+		tracingServiceName = DefaultTracingServiceName
+	}
+	tracingHeaders, err := parseHeaders("OTEL_EXPORTER_OTLP_HEADERS", l.get("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		l.problems = append(l.problems, err)
+	}
 
 	cfg := &Config{
-		OpConnectHost:         os.Getenv("OP_CONNECT_HOST"),
-		OpVaultUUID:           os.Getenv("OP_VAULT"),
-		OpServiceAccountToken: strings.TrimSpace(os.Getenv("OP_SERVICE_ACCOUNT_TOKEN")),
-		KomodoHost:            os.Getenv("KOMODO_HOST"),
-		KomodoAPIKey:          os.Getenv("KOMODO_API_KEY"),
-		KomodoAPISecret:       os.Getenv("KOMODO_API_SECRET"),
-		LogLevel:              os.Getenv("LOG_LEVEL"),
-		SyncInterval:          syncInterval, // Set from env var or default
+		OpConnectHost:           l.get("OP_CONNECT_HOST"),
+		OpVaultUUID:             l.get("OP_VAULT"),
+		OpServiceAccountToken:   strings.TrimSpace(l.get("OP_SERVICE_ACCOUNT_TOKEN")),
+		LogLevel:                l.get("LOG_LEVEL"),
+		LogFormat:               l.get("LOG_FORMAT"),
+		SyncInterval:            syncInterval, // Set from env var or default
+		SyncSchedule:            syncSchedule,
+		FreezeWindows:           freezeWindows,
+		FreezeMode:              freezeMode,
+		FailureBackoffAfter:     l.nonNegativeInt("FAILURE_BACKOFF_AFTER"),
+		FailureBackoffMax:       l.duration("FAILURE_BACKOFF_MAX", DefaultFailureBackoffMax),
+		FailureExitAfter:        l.nonNegativeInt("FAILURE_EXIT_AFTER"),
+		ChangePollInterval:      l.duration("CHANGE_POLL_INTERVAL", 0),
+		HTTPAddr:                strings.TrimSpace(l.get("HTTP_ADDR")),
+		ReadinessIntervals:      l.positiveInt("READINESS_INTERVALS", DefaultReadinessIntervals),
+		ControlToken:            strings.TrimSpace(l.get("CONTROL_API_TOKEN")),
+		ConfigFile:              path,
+		ConfigWatchInterval:     l.duration("CONFIG_WATCH_INTERVAL", 0),
+		SecretPolicy:            secretPolicy,
+		NonSecretFieldTypes:     splitList(strings.ToUpper(nonSecretFieldTypes)),
+		SecretOverrides:         secretOverrides,
+		RedeployMode:            redeployMode,
+		RedeployAllowlist:       splitList(l.get("REDEPLOY_ALLOWLIST")),
+		RedeployProcedure:       strings.TrimSpace(l.get("REDEPLOY_PROCEDURE")),
+		PostSyncProcedure:       strings.TrimSpace(l.get("POST_SYNC_PROCEDURE")),
+		PostSyncAction:          strings.TrimSpace(l.get("POST_SYNC_ACTION")),
+		TracingEndpoint:         l.tracingEndpoint(),
+		TracingServiceName:      tracingServiceName,
+		TracingHeaders:          tracingHeaders,
+		AuditLogPath:            strings.TrimSpace(l.get("AUDIT_LOG_PATH")),
+		AuditLogMaxSizeMB:       l.positiveInt("AUDIT_LOG_MAX_SIZE_MB", DefaultAuditLogMaxSizeMB),
+		AuditLogMaxFiles:        l.positiveInt("AUDIT_LOG_MAX_FILES", DefaultAuditLogMaxFiles),
+		AuditFingerprintKey:     l.get("AUDIT_FINGERPRINT_KEY"),
+		StateDir:                strings.TrimSpace(l.get("STATE_DIR")),
+		HistorySize:             l.positiveInt("HISTORY_SIZE", DefaultHistorySize),
+		MaxDeletions:            l.nonNegativeInt("MAX_DELETIONS"),
+		WebhookFailureThreshold: l.positiveInt("WEBHOOK_CONSECUTIVE_FAILURES", DefaultWebhookFailureThreshold),
 	}
+	cfg.OpVaults = splitList(cfg.OpVaultUUID)
 
 	// Validate required fields
 	if cfg.OpConnectHost == "" {
-		return nil, fmt.Errorf("OP_CONNECT_HOST environment variable not set")
+		l.notSet("OP_CONNECT_HOST", "OP_CONNECT_HOST environment variable not set", "onepassword.host")
 	}
-	if cfg.OpVaultUUID == "" {
-		return nil, fmt.Errorf("OP_VAULT environment variable (vault UUID) not set")
+	if len(cfg.OpVaults) == 0 {
+		l.notSet("OP_VAULT", "OP_VAULT environment variable (vault UUID) not set", "onepassword.vaults")
 	}
 	if cfg.OpServiceAccountToken == "" {
-		return nil, fmt.Errorf("OP_SERVICE_ACCOUNT_TOKEN environment variable not set or is only whitespace")
+		l.notSet("OP_SERVICE_ACCOUNT_TOKEN", "OP_SERVICE_ACCOUNT_TOKEN environment variable not set or is only whitespace", "onepassword.token")
 	}
-	if cfg.KomodoHost == "" {
-		return nil, fmt.Errorf("KOMODO_HOST environment variable not set")
+	cfg.KomodoTargets = l.loadKomodoTargets(cfg.MaxDeletions)
+	cfg.Webhooks = l.loadWebhooks()
+	cfg.SecretFiles = l.files
+
+	if cfg.AuditLogPath != "" && cfg.AuditFingerprintKey == "" && cfg.StateDir == "" {
+		l.problem("AUDIT_FINGERPRINT_KEY must be set when AUDIT_LOG_PATH is, unless STATE_DIR is set to keep a generated key")
 	}
-	if cfg.KomodoAPIKey == "" {
-		return nil, fmt.Errorf("KOMODO_API_KEY environment variable not set")
+	switch cfg.SecretPolicy {
+	case SecretPolicyAll, SecretPolicyFieldType:
+	default:
+		l.problem("SECRET_POLICY must be one of %q or %q, got %q", SecretPolicyAll, SecretPolicyFieldType, cfg.SecretPolicy)
 	}
-	if cfg.KomodoAPISecret == "" {
-		return nil, fmt.Errorf("KOMODO_API_SECRET environment variable not set")
+	switch cfg.FreezeMode {
+	case FreezeModeAll, FreezeModeDeletes:
+	default:
+		l.problem("FREEZE_MODE must be one of %q or %q, got %q", FreezeModeAll, FreezeModeDeletes, cfg.FreezeMode)
+	}
+	switch cfg.RedeployMode {
+	case RedeployOff, RedeployDryRun, RedeployDeploy:
+	default:
+		l.problem("REDEPLOY_MODE must be one of %q, %q or %q, got %q", RedeployOff, RedeployDryRun, RedeployDeploy, cfg.RedeployMode)
+	}
+	if len(l.problems) > 0 {
+		return nil, errors.Join(l.problems...)
 	}
 
-	// Resolve Vault ID (currently just using the provided UUID)
-	cfg.OpVaultID = cfg.OpVaultUUID
+	// Resolve Vault ID (currently just using the first provided UUID)
+	cfg.OpVaultID = cfg.OpVaults[0]
 
 	// Ensure hosts start with http:// or https://
 	if !strings.HasPrefix(cfg.OpConnectHost, "http") {
 		cfg.OpConnectHost = "http://" + cfg.OpConnectHost
 	}
-	if !strings.HasPrefix(cfg.KomodoHost, "http") {
-		cfg.KomodoHost = "http://" + cfg.KomodoHost
-	}
 
 	// Remove trailing slashes
 	cfg.OpConnectHost = strings.TrimSuffix(cfg.OpConnectHost, "/")
-	cfg.KomodoHost = strings.TrimSuffix(cfg.KomodoHost, "/")
 
 	// Logging of loaded config will be done in main after setting log level
 	// We will also log the SyncInterval there.
 
 	return cfg, nil
 }
+
+// LoadStateDir reads only STATE_DIR, from the environment or the config file at
+// path, for commands working on the stored state without syncing. Problems of
+// other settings are not reported; only an unreadable or malformed file, or a
+// STATE_DIR_FILE that cannot be read, is an error.
+func LoadStateDir(path string) (string, error) {
+	l := &loader{}
+	if path != "" {
+		file, err := loadFile(path)
+		if err != nil {
+			return "", err
+		}
+		l.file = file
+	}
+	stateDir := strings.TrimSpace(l.get("STATE_DIR"))
+	if len(l.problems) > 0 {
+		return "", errors.Join(l.problems...)
+	}
+	return stateDir, nil
+}
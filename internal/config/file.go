@@ -0,0 +1,418 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fileSettings holds the settings of a config file by the environment variable
+// they stand for, so that the environment overrides them one by one.
+type fileSettings struct {
+	path     string
+	values   map[string]string
+	webhooks []Webhook
+	mappings []map[string]interface{} // Applied once the targets are read
+	problems []error
+}
+
+// fileKeys maps the keys of a config file to the environment variables they
+// stand for. Keys of sections are prefixed with the section name.
+var fileKeys = map[string]string{
+	"log_level":                    "LOG_LEVEL",
+	"log_format":                   "LOG_FORMAT",
+	"sync_interval":                "SYNC_INTERVAL",
+	"sync_schedule":                "SYNC_SCHEDULE",
+	"change_poll_interval":         "CHANGE_POLL_INTERVAL",
+	"http_addr":                    "HTTP_ADDR",
+	"readiness_intervals":          "READINESS_INTERVALS",
+	"control_api_token":            "CONTROL_API_TOKEN",
+	"config_watch_interval":        "CONFIG_WATCH_INTERVAL",
+	"secret_policy":                "SECRET_POLICY",
+	"non_secret_field_types":       "NON_SECRET_FIELD_TYPES",
+	"secret_overrides":             "SECRET_OVERRIDES",
+	"max_deletions":                "MAX_DELETIONS",
+	"redeploy_mode":                "REDEPLOY_MODE",
+	"redeploy_allowlist":           "REDEPLOY_ALLOWLIST",
+	"redeploy_procedure":           "REDEPLOY_PROCEDURE",
+	"post_sync_procedure":          "POST_SYNC_PROCEDURE",
+	"post_sync_action":             "POST_SYNC_ACTION",
+	"freeze_windows":               "FREEZE_WINDOWS",
+	"freeze_mode":                  "FREEZE_MODE",
+	"failure_backoff_after":        "FAILURE_BACKOFF_AFTER",
+	"failure_backoff_max":          "FAILURE_BACKOFF_MAX",
+	"failure_exit_after":           "FAILURE_EXIT_AFTER",
+	"state_dir":                    "STATE_DIR",
+	"history_size":                 "HISTORY_SIZE",
+	"webhook_consecutive_failures": "WEBHOOK_CONSECUTIVE_FAILURES",
+	"onepassword.host":             "OP_CONNECT_HOST",
+	"onepassword.token":            "OP_SERVICE_ACCOUNT_TOKEN",
+	"onepassword.vaults":           "OP_VAULT",
+	"tracing.endpoint":             "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+	"tracing.service_name":         "OTEL_SERVICE_NAME",
+	"tracing.headers":              "OTEL_EXPORTER_OTLP_HEADERS",
+	"audit.path":                   "AUDIT_LOG_PATH",
+	"audit.max_size_mb":            "AUDIT_LOG_MAX_SIZE_MB",
+	"audit.max_files":              "AUDIT_LOG_MAX_FILES",
+	"audit.fingerprint_key":        "AUDIT_FINGERPRINT_KEY",
+}
+
+// fileSections are the objects of a config file grouping keys.
+var fileSections = map[string]bool{"onepassword": true, "tracing": true, "audit": true}
+
+// fileMaps are the keys of a config file whose value is an object of name-value pairs.
+var fileMaps = map[string]bool{"secret_overrides": true, "tracing.headers": true}
+
+// targetFileKeys maps the keys of a "targets" entry to the suffix of the
+// environment variables they stand for.
+var targetFileKeys = map[string]string{
+	"host":          "HOST",
+	"api_key":       "API_KEY",
+	"api_secret":    "API_SECRET",
+	"include":       "INCLUDE",
+	"exclude":       "EXCLUDE",
+	"max_deletions": "MAX_DELETIONS",
+}
+
+// loadFile reads a JSON config file. Unknown keys and invalid values are
+// collected as problems; only an unreadable or malformed file is an error.
+func loadFile(path string) (*fileSettings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	doc, err := parseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file: %s: %w", path, err)
+	}
+
+	f := &fileSettings{path: path, values: make(map[string]string)}
+	f.read("", doc)
+	f.applyMappings()
+	return f, nil
+}
+
+// parseJSON decodes a config file holding a single JSON object. Numbers are
+// kept as written, so that integers are told apart from fractions. Syntax
+// errors are located by line.
+func parseJSON(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	err := decoder.Decode(&doc)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the top-level object")
+	}
+	if err == nil && doc == nil {
+		err = errors.New("the file must hold a JSON object")
+	}
+	if err == nil {
+		return doc, nil
+	}
+
+	var offset int64 = -1
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxError):
+		offset = syntaxError.Offset
+	case errors.As(err, &typeError):
+		err = errors.New("the file must hold a JSON object")
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		err = errors.New("unexpected end of file")
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		return nil, err
+	}
+	line := 1 + bytes.Count(data[:min(int(offset), len(data))], []byte("\n"))
+	return nil, fmt.Errorf("line %d: %w", line, err)
+}
+
+func (f *fileSettings) problem(format string, args ...interface{}) {
+	f.problems = append(f.problems, fmt.Errorf("%s: %s", f.path, fmt.Sprintf(format, args...)))
+}
+
+// read maps the keys of a table to environment variables.
+func (f *fileSettings) read(prefix string, table map[string]interface{}) {
+	for _, key := range sortedKeys(table) {
+		path, value := prefix+key, table[key]
+		if name, ok := fileKeys[path]; ok {
+			if converted, ok := f.convert(path, value); ok {
+				f.values[name] = converted
+			}
+			continue
+		}
+		if name, ok := fileKeys[strings.TrimSuffix(path, "_file")]; ok && strings.HasSuffix(path, "_file") {
+			f.readValueFile(path, name, value)
+			continue
+		}
+		if section, ok := value.(map[string]interface{}); ok && fileSections[path] {
+			f.read(path+".", section)
+			continue
+		}
+		switch path {
+		case "targets":
+			f.readTargets(value)
+		case "webhooks":
+			f.readWebhooks(value)
+		case "mappings":
+			list, _ := value.([]interface{})
+			mappings, ok := arrayOfObjects(list)
+			if !ok {
+				f.problem("mappings must be an array of objects")
+				continue
+			}
+			f.mappings = mappings
+		default:
+			f.problem("unknown setting %q", path)
+		}
+	}
+}
+
+// convert renders a value as it would be written in the environment variable:
+// arrays and objects as comma-separated lists.
+func (f *fileSettings) convert(path string, value interface{}) (string, bool) {
+	separator := ","
+	if path == "freeze_windows" {
+		separator = ";"
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		entries := make([]string, 0, len(v))
+		for _, element := range v {
+			entry, ok := scalarString(element)
+			if !ok || strings.Contains(entry, separator) {
+				f.problem("%s must be a list of values without %q", path, separator)
+				return "", false
+			}
+			entries = append(entries, entry)
+		}
+		return strings.Join(entries, separator), true
+	case map[string]interface{}:
+		if !fileMaps[path] {
+			f.problem("%s must be a single value, not an object", path)
+			return "", false
+		}
+		pairs := make([]string, 0, len(v))
+		for _, key := range sortedKeys(v) {
+			entry, ok := scalarString(v[key])
+			if !ok || strings.Contains(key+entry, ",") {
+				f.problem("%s.%s must be a single value without ','", path, key)
+				return "", false
+			}
+			pairs = append(pairs, key+"="+entry)
+		}
+		return strings.Join(pairs, ","), true
+	default:
+		if fileMaps[path] {
+			f.problem("%s must be an object of name-value pairs", path)
+			return "", false
+		}
+		entry, ok := scalarString(value)
+		if !ok {
+			f.problem("%s must be a string, an integer or a boolean", path)
+		}
+		return entry, ok
+	}
+}
+
+// readValueFile maps a key ending in "_file" to the NAME_FILE variable of the
+// setting it holds the value of. Relative paths are resolved against the
+// directory of the config file.
+func (f *fileSettings) readValueFile(path, name string, value interface{}) {
+	file, ok := value.(string)
+	if !ok || strings.TrimSpace(file) == "" {
+		f.problem("%s must be a file path", path)
+		return
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(f.path), file)
+	}
+	f.values[name+"_FILE"] = file
+}
+
+// readTargets maps the "targets" array to the KOMODO_* variables of named
+// targets, or to those of the default target if there is a single one without
+// a name.
+func (f *fileSettings) readTargets(value interface{}) {
+	list, _ := value.([]interface{})
+	targets, ok := arrayOfObjects(list)
+	if !ok {
+		f.problem("targets must be an array of objects")
+		return
+	}
+
+	var names []string
+	for i, target := range targets {
+		prefix := "KOMODO_"
+		name, _ := target["name"].(string)
+		name = strings.TrimSpace(name)
+		switch {
+		case name != "":
+			prefix = targetEnvPrefix(name)
+			names = append(names, name)
+		case len(targets) > 1:
+			f.problem("targets[%d].name is required when several targets are set", i)
+			continue
+		}
+
+		for _, key := range sortedKeys(target) {
+			if key == "name" {
+				continue
+			}
+			if suffix, known := targetFileKeys[strings.TrimSuffix(key, "_file")]; known && strings.HasSuffix(key, "_file") {
+				f.readValueFile(fmt.Sprintf("targets[%d].%s", i, key), prefix+suffix, target[key])
+				continue
+			}
+			suffix, known := targetFileKeys[key]
+			if !known {
+				f.problem("unknown setting \"targets[%d].%s\"", i, key)
+				continue
+			}
+			if converted, ok := f.convert(fmt.Sprintf("targets[%d].%s", i, key), target[key]); ok {
+				f.values[prefix+suffix] = converted
+			}
+		}
+	}
+	if len(names) > 0 {
+		f.values["KOMODO_TARGETS"] = strings.Join(names, ",")
+	}
+}
+
+// applyMappings adds the item patterns of each "mappings" entry to the include
+// patterns of the targets it names, as if listed in their "include". A target
+// named by a mapping thus only receives the items mapped or included to it.
+// Names must be among the named targets of the file, if it has any.
+func (f *fileSettings) applyMappings() {
+	named := make(map[string]bool)
+	for _, name := range splitList(f.values["KOMODO_TARGETS"]) {
+		named[name] = true
+	}
+	for i, mapping := range f.mappings {
+		var items, targets []string
+		for _, key := range sortedKeys(mapping) {
+			setting := fmt.Sprintf("mappings[%d].%s", i, key)
+			switch key {
+			case "items", "targets":
+				list, ok := mapping[key].([]interface{})
+				if !ok || len(list) == 0 {
+					f.problem("%s must be a non-empty list", setting)
+					continue
+				}
+				converted, ok := f.convert(setting, list)
+				if !ok {
+					continue
+				}
+				if key == "items" {
+					items = splitList(converted)
+				} else {
+					targets = splitList(converted)
+				}
+			default:
+				f.problem("unknown setting %q", setting)
+			}
+		}
+		if items == nil || targets == nil {
+			if _, ok := mapping["items"]; !ok {
+				f.problem("mappings[%d].items is required", i)
+			}
+			if _, ok := mapping["targets"]; !ok {
+				f.problem("mappings[%d].targets is required", i)
+			}
+			continue
+		}
+
+		for _, name := range targets {
+			prefix := targetEnvPrefix(name)
+			switch {
+			case len(named) > 0 && !named[name]:
+				f.problem("mappings[%d].targets: no target named %q", i, name)
+				continue
+			case len(named) == 0 && name == DefaultTargetName:
+				prefix = "KOMODO_"
+			}
+			include := append(splitList(f.values[prefix+"INCLUDE"]), items...)
+			f.values[prefix+"INCLUDE"] = strings.Join(include, ",")
+		}
+	}
+}
+
+// readWebhooks reads the "webhooks" array. They are validated with the rest of
+// the configuration.
+func (f *fileSettings) readWebhooks(value interface{}) {
+	list, _ := value.([]interface{})
+	webhooks, ok := arrayOfObjects(list)
+	if !ok {
+		f.problem("webhooks must be an array of objects")
+		return
+	}
+	for i, table := range webhooks {
+		var webhook Webhook
+		for _, key := range sortedKeys(table) {
+			setting := fmt.Sprintf("webhooks[%d].%s", i, key)
+			converted, ok := f.convert(setting, table[key])
+			if !ok {
+				continue
+			}
+			switch key {
+			case "url":
+				webhook.URL = strings.TrimSpace(converted)
+			case "format":
+				webhook.Format = strings.ToLower(strings.TrimSpace(converted))
+			case "events":
+				webhook.Events = splitList(strings.ToLower(converted))
+			default:
+				f.problem("unknown setting %q", setting)
+			}
+		}
+		f.webhooks = append(f.webhooks, webhook)
+	}
+}
+
+// scalarString renders a string, integer or boolean.
+func scalarString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		if _, err := v.Int64(); err != nil {
+			return v.String(), false // Fractions and exponents, which no setting takes
+		}
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return fmt.Sprint(v), false
+	}
+}
+
+// arrayOfObjects returns the elements of a non-empty array if all are objects.
+func arrayOfObjects(values []interface{}) ([]map[string]interface{}, bool) {
+	objects := make([]map[string]interface{}, 0, len(values))
+	for _, value := range values {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		objects = append(objects, object)
+	}
+	return objects, len(objects) > 0
+}
+
+func sortedKeys(table map[string]interface{}) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,390 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFile writes a file in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// loadTestFile loads a config file with the given content.
+func loadTestFile(t *testing.T, content string) *fileSettings {
+	t.Helper()
+	f, err := loadFile(writeFile(t, t.TempDir(), "komodo-op.json", content))
+	if err != nil {
+		t.Fatalf("loadFile() error = %v", err)
+	}
+	return f
+}
+
+func TestLoadFileValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "scalars",
+			content: `{"sync_interval": "5m", "history_size": 20}`,
+			want:    map[string]string{"SYNC_INTERVAL": "5m", "HISTORY_SIZE": "20"},
+		},
+		{
+			name:    "sections",
+			content: `{"onepassword": {"host": "op:8080"}, "audit": {"max_files": 3}}`,
+			want:    map[string]string{"OP_CONNECT_HOST": "op:8080", "AUDIT_LOG_MAX_FILES": "3"},
+		},
+		{
+			name:    "dotted keys",
+			content: `{"onepassword.vaults": ["a", "b"]}`,
+			want:    map[string]string{"OP_VAULT": "a,b"},
+		},
+		{
+			name:    "lists",
+			content: `{"non_secret_field_types": ["STRING", "URL"], "readiness_intervals": 2}`,
+			want:    map[string]string{"NON_SECRET_FIELD_TYPES": "STRING,URL", "READINESS_INTERVALS": "2"},
+		},
+		{
+			name:    "freeze windows joined by semicolons",
+			content: `{"freeze_windows": ["0 18 * * 5 for 62h", "0 0 24 12 * for 48h"]}`,
+			want:    map[string]string{"FREEZE_WINDOWS": "0 18 * * 5 for 62h;0 0 24 12 * for 48h"},
+		},
+		{
+			name:    "maps",
+			content: `{"secret_overrides": {"db/user": false, "api/key": true}}`,
+			want:    map[string]string{"SECRET_OVERRIDES": "api/key=true,db/user=false"},
+		},
+		{
+			name:    "tracing headers",
+			content: `{"tracing": {"headers": {"Authorization": "Bearer x"}}}`,
+			want:    map[string]string{"OTEL_EXPORTER_OTLP_HEADERS": "Authorization=Bearer x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadTestFile(t, tt.content)
+			if len(f.problems) > 0 {
+				t.Fatalf("problems = %v", f.problems)
+			}
+			if !reflect.DeepEqual(f.values, tt.want) {
+				t.Errorf("values = %v, want %v", f.values, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", `{"sync_intervall": "5m"}`, `unknown setting "sync_intervall"`},
+		{"unknown section key", `{"onepassword": {"vault": "a"}}`, `unknown setting "onepassword.vault"`},
+		{"list with separator", `{"onepassword.vaults": ["a,b"]}`, `onepassword.vaults must be a list of values without ","`},
+		{"list of objects", `{"onepassword.vaults": [{"a": 1}]}`, "onepassword.vaults must be a list of values"},
+		{"window with separator", `{"freeze_windows": ["a;b"]}`, `freeze_windows must be a list of values without ";"`},
+		{"object for a value", `{"sync_interval": {"a": 1}}`, "sync_interval must be a single value, not an object"},
+		{"fraction", `{"history_size": 1.5}`, "history_size must be a string, an integer or a boolean"},
+		{"null", `{"log_level": null}`, "log_level must be a string, an integer or a boolean"},
+		{"value for a map", `{"secret_overrides": "a=true"}`, "secret_overrides must be an object of name-value pairs"},
+		{"map entry with comma", `{"secret_overrides": {"a,b": true}}`, "secret_overrides.a,b must be a single value without ','"},
+		{"non-string file path", `{"control_api_token_file": 1}`, "control_api_token_file must be a file path"},
+		{"targets not objects", `{"targets": [1]}`, "targets must be an array of objects"},
+		{"unnamed among several targets", `{"targets": [{"name": "a"}, {"host": "b"}]}`, "targets[1].name is required when several targets are set"},
+		{"unknown target key", `{"targets": [{"hots": "a"}]}`, `unknown setting "targets[0].hots"`},
+		{"webhooks not objects", `{"webhooks": "https://example.com"}`, "webhooks must be an array of objects"},
+		{"unknown webhook key", `{"webhooks": [{"url": "https://example.com", "token": "x"}]}`, `unknown setting "webhooks[0].token"`},
+		{"mappings not objects", `{"mappings": ["a"]}`, "mappings must be an array of objects"},
+		{"mapping without targets", `{"mappings": [{"items": ["a"]}]}`, "mappings[0].targets is required"},
+		{"mapping with empty items", `{"mappings": [{"items": [], "targets": ["default"]}]}`, "mappings[0].items must be a non-empty list"},
+		{"unknown mapping key", `{"mappings": [{"items": ["a"], "targets": ["default"], "exclude": ["b"]}]}`, `unknown setting "mappings[0].exclude"`},
+		{"mapping to an unknown target", `{"targets": [{"name": "prod"}], "mappings": [{"items": ["a"], "targets": ["staging"]}]}`, `mappings[0].targets: no target named "staging"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadTestFile(t, tt.content)
+			if len(f.problems) != 1 {
+				t.Fatalf("problems = %v, want one containing %q", f.problems, tt.want)
+			}
+			got := f.problems[0].Error()
+			if !strings.HasPrefix(got, f.path+": ") || !strings.Contains(got, tt.want) {
+				t.Errorf("problem = %q, want %q prefixed with the file path", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFileInvalid(t *testing.T) {
+	if _, err := loadFile(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "failed to read config file") {
+		t.Errorf("missing file: error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax error", "{\n  \"a\": 1,\n  \"b\" 2\n}", "line 3: invalid character"},
+		{"truncated", "{\n  \"a\": [1,\n", "line 3: unexpected end of file"},
+		{"not an object", `["a"]`, "the file must hold a JSON object"},
+		{"null", "null", "the file must hold a JSON object"},
+		{"trailing data", `{"a": 1} {"b": 2}`, "unexpected data after the top-level object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), "bad.json", tt.content)
+			_, err := loadFile(path)
+			if err == nil || !strings.Contains(err.Error(), "invalid config file: "+path+": "+tt.want) {
+				t.Errorf("loadFile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name:    "single unnamed target",
+			content: `{"targets": [{"host": "komodo:9120", "include": ["a", "b"]}]}`,
+			want:    map[string]string{"KOMODO_HOST": "komodo:9120", "KOMODO_INCLUDE": "a,b"},
+		},
+		{
+			name:    "single named target",
+			content: `{"targets": [{"name": "prod", "host": "komodo:9120"}]}`,
+			want:    map[string]string{"KOMODO_TARGETS": "prod", "KOMODO_PROD_HOST": "komodo:9120"},
+		},
+		{
+			name:    "several named targets",
+			content: `{"targets": [{"name": "Prod EU", "api_key": "k1"}, {"name": "staging", "exclude": ["x"]}]}`,
+			want: map[string]string{
+				"KOMODO_TARGETS":         "Prod EU,staging",
+				"KOMODO_PROD_EU_API_KEY": "k1",
+				"KOMODO_STAGING_EXCLUDE": "x",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadTestFile(t, tt.content)
+			if len(f.problems) > 0 {
+				t.Fatalf("problems = %v", f.problems)
+			}
+			if !reflect.DeepEqual(f.values, tt.want) {
+				t.Errorf("values = %v, want %v", f.values, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+	}{
+		{
+			name: "items mapped to named targets",
+			content: `{
+  "targets": [{"name": "prod", "include": ["App *"]}, {"name": "staging"}],
+  "mappings": [
+    {"items": ["tag:shared", "Common"], "targets": ["prod", "staging"]},
+    {"items": ["tag:staging"], "targets": ["staging"]}
+  ]
+}`,
+			want: map[string]string{
+				"KOMODO_TARGETS":         "prod,staging",
+				"KOMODO_PROD_INCLUDE":    "App *,tag:shared,Common",
+				"KOMODO_STAGING_INCLUDE": "tag:shared,Common,tag:staging",
+			},
+		},
+		{
+			name:    "default target",
+			content: `{"mappings": [{"items": ["tag:komodo"], "targets": ["default"]}]}`,
+			want:    map[string]string{"KOMODO_INCLUDE": "tag:komodo"},
+		},
+		{
+			name:    "targets configured in the environment",
+			content: `{"mappings": [{"items": ["tag:eu"], "targets": ["Prod EU"]}]}`,
+			want:    map[string]string{"KOMODO_PROD_EU_INCLUDE": "tag:eu"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := loadTestFile(t, tt.content)
+			if len(f.problems) > 0 {
+				t.Fatalf("problems = %v", f.problems)
+			}
+			if !reflect.DeepEqual(f.values, tt.want) {
+				t.Errorf("values = %v, want %v", f.values, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadWebhooks(t *testing.T) {
+	f := loadTestFile(t, `{"webhooks": [
+  {"url": " https://hooks.example.com/a ", "format": "Slack", "events": ["Changes", "errors"]},
+  {"url": "https://ntfy.example.com/b"}
+]}`)
+	if len(f.problems) > 0 {
+		t.Fatalf("problems = %v", f.problems)
+	}
+	want := []Webhook{
+		{URL: "https://hooks.example.com/a", Format: WebhookFormatSlack, Events: []string{EventChanges, EventErrors}},
+		{URL: "https://ntfy.example.com/b"},
+	}
+	if !reflect.DeepEqual(f.webhooks, want) {
+		t.Errorf("webhooks = %+v, want %+v", f.webhooks, want)
+	}
+}
+
+func TestReadValueFile(t *testing.T) {
+	dir := t.TempDir()
+	absolute := filepath.Join(dir, "secrets", "token")
+	f := loadTestFile(t, `{
+  "control_api_token_file": "control-token",
+  "onepassword": {"token_file": "`+absolute+`"},
+  "targets": [{"api_secret_file": "../komodo-secret"}]
+}`)
+	if len(f.problems) > 0 {
+		t.Fatalf("problems = %v", f.problems)
+	}
+	configDir := filepath.Dir(f.path)
+	want := map[string]string{
+		"CONTROL_API_TOKEN_FILE":        filepath.Join(configDir, "control-token"),
+		"OP_SERVICE_ACCOUNT_TOKEN_FILE": absolute,
+		"KOMODO_API_SECRET_FILE":        filepath.Join(filepath.Dir(configDir), "komodo-secret"),
+	}
+	if !reflect.DeepEqual(f.values, want) {
+		t.Errorf("values = %v, want %v", f.values, want)
+	}
+}
+
+// clearEnv unsets every variable a config file key stands for, along with
+// those of the default target and the webhook, for the duration of a test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	names := []string{"KOMODO_TARGETS", "WEBHOOK_URL", "WEBHOOK_FORMAT", "WEBHOOK_EVENTS"}
+	for _, name := range fileKeys {
+		names = append(names, name)
+	}
+	for _, suffix := range targetFileKeys {
+		names = append(names, "KOMODO_"+suffix, "KOMODO_PROD_"+suffix)
+	}
+	for _, name := range names {
+		t.Setenv(name, "")
+		t.Setenv(name+"_FILE", "")
+	}
+}
+
+func TestLoadConfigFromFile(t *testing.T) {
+	clearEnv(t)
+	dir := t.TempDir()
+	writeFile(t, dir, "op-token", "op-secret\n")
+	path := writeFile(t, dir, "komodo-op.json", `{
+  "sync_interval": "5m",
+  "log_level": "debug",
+  "max_deletions": 10,
+  "onepassword": {
+    "host": "op:8080/",
+    "vaults": ["vault-a", "vault-b"],
+    "token_file": "op-token"
+  },
+  "targets": [{
+    "name": "prod",
+    "host": "komodo:9120",
+    "api_key": "key",
+    "api_secret": "secret",
+    "max_deletions": 3
+  }],
+  "mappings": [{"items": ["tag:prod"], "targets": ["prod"]}]
+}`)
+
+	// The environment takes precedence over the file
+	t.Setenv("LOG_LEVEL", "warn")
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.SyncInterval != "5m" || cfg.LogLevel != "warn" {
+		t.Errorf("SyncInterval, LogLevel = %q, %q, want \"5m\", \"warn\"", cfg.SyncInterval, cfg.LogLevel)
+	}
+	if cfg.OpConnectHost != "http://op:8080" || cfg.OpVaultID != "vault-a" || cfg.OpServiceAccountToken != "op-secret" {
+		t.Errorf("1Password settings = %q, %q, %q", cfg.OpConnectHost, cfg.OpVaultID, cfg.OpServiceAccountToken)
+	}
+	want := []KomodoTarget{{Name: "prod", Host: "http://komodo:9120", APIKey: "key", APISecret: "secret", Include: []string{"tag:prod"}, MaxDeletions: 3}}
+	if !reflect.DeepEqual(cfg.KomodoTargets, want) {
+		t.Errorf("KomodoTargets = %+v, want %+v", cfg.KomodoTargets, want)
+	}
+	if cfg.FreezeMode != FreezeModeAll {
+		t.Errorf("FreezeMode = %q, want %q by default", cfg.FreezeMode, FreezeModeAll)
+	}
+	if cfg.MaxDeletions != 10 {
+		t.Errorf("MaxDeletions = %d, want 10", cfg.MaxDeletions)
+	}
+	if want := []string{filepath.Join(dir, "op-token")}; !reflect.DeepEqual(cfg.SecretFiles, want) {
+		t.Errorf("SecretFiles = %v, want %v", cfg.SecretFiles, want)
+	}
+}
+
+func TestLoadConfigProblems(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, t.TempDir(), "komodo-op.json", `{
+  "sync_interval": "soon",
+  "unknown": 1,
+  "audit": {"path": "/var/log/komodo-op/audit.jsonl"},
+  "webhooks": [{"url": "ftp://example.com"}]
+}`)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("LoadConfig() succeeded, want problems")
+	}
+	for _, want := range []string{
+		path + ": unknown setting \"unknown\"",
+		"SYNC_INTERVAL must be a positive duration (e.g., \"1h\"), got \"soon\"",
+		"OP_CONNECT_HOST environment variable not set (nor onepassword.host in " + path + ")",
+		"KOMODO_HOST environment variable not set (nor host of targets in " + path + ")",
+		path + ": webhooks[0].url must be an http:// or https:// URL",
+		"AUDIT_FINGERPRINT_KEY must be set when AUDIT_LOG_PATH is, unless STATE_DIR is set to keep a generated key",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestLoadStateDir(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STATE_DIR", "")
+	// Settings other than STATE_DIR are neither required nor validated
+	path := writeFile(t, t.TempDir(), "komodo-op.json", `{"state_dir": "/data", "sync_interval": "soon", "unknown": 1}`)
+	if stateDir, err := LoadStateDir(path); err != nil || stateDir != "/data" {
+		t.Errorf("LoadStateDir() = %q, %v, want \"/data\"", stateDir, err)
+	}
+
+	t.Setenv("STATE_DIR", "/state")
+	if stateDir, err := LoadStateDir(path); err != nil || stateDir != "/state" {
+		t.Errorf("LoadStateDir() = %q, %v, want the environment to take precedence", stateDir, err)
+	}
+
+	t.Setenv("STATE_DIR", "")
+	t.Setenv("STATE_DIR_FILE", "/nonexistent")
+	if _, err := LoadStateDir(""); err == nil {
+		t.Error("LoadStateDir() with an unreadable STATE_DIR_FILE succeeded")
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loader reads settings from the environment, falling back to the config file,
+// and collects every problem found rather than stopping at the first.
+type loader struct {
+	file      *fileSettings // nil without config file
+	problems  []error
+	fromFiles map[string]string // Values read from NAME_FILE files, by NAME
+	files     []string          // Files values were read from
+}
+
+// get returns the value of a setting by its environment variable name. Empty
+// environment variables fall back to the config file as well, as compose files
+// often declare variables without setting them. In either, NAME_FILE names a
+// file holding the value instead, keeping credentials out of the environment.
+func (l *loader) get(name string) string {
+	if value, ok := l.fromFiles[name]; ok {
+		return value
+	}
+	value, path := os.Getenv(name), os.Getenv(name+"_FILE")
+	if value != "" && path != "" {
+		l.problem("%s and %s_FILE are both set, only one may be", name, name)
+	}
+	if value == "" && path == "" && l.file != nil {
+		value, path = l.file.values[name], l.file.values[name+"_FILE"]
+	}
+	if value != "" || path == "" {
+		return value
+	}
+	return l.readFile(name, path)
+}
+
+// readFile reads the value of a setting from the file at path, without the
+// trailing newline most editors and secret stores add.
+func (l *loader) readFile(name, path string) string {
+	if l.fromFiles == nil {
+		l.fromFiles = make(map[string]string)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.problem("%s_FILE: %v", name, err)
+		l.fromFiles[name] = ""
+		return ""
+	}
+	value := strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		l.problem("%s_FILE: %s is empty", name, path)
+	}
+	l.fromFiles[name] = value
+	l.files = append(l.files, path)
+	return value
+}
+
+func (l *loader) problem(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Errorf(format, args...))
+}
+
+// notSet records a missing required setting, pointing to the config file key
+// it may also be set with, unless reading it from a file already failed.
+func (l *loader) notSet(name, message, key string) {
+	if _, fromFile := l.fromFiles[name]; fromFile {
+		return
+	}
+	if l.file != nil {
+		message += fmt.Sprintf(" (nor %s in %s)", key, l.file.path)
+	}
+	l.problems = append(l.problems, fmt.Errorf("%s", message))
+}
+
+// positiveInt reads a positive integer setting, or returns def if it is unset.
+func (l *loader) positiveInt(name string, def int) int {
+	value := strings.TrimSpace(l.get(name))
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		l.problem("%s must be a positive integer, got %q", name, value)
+		return def
+	}
+	return parsed
+}
+
+// nonNegativeInt reads a non-negative integer setting, or returns 0 if it is unset.
+func (l *loader) nonNegativeInt(name string) int {
+	value := strings.TrimSpace(l.get(name))
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		l.problem("%s must be a non-negative integer, got %q", name, value)
+		return 0
+	}
+	return parsed
+}
+
+// duration reads a positive duration setting, or returns def if it is unset.
+func (l *loader) duration(name string, def time.Duration) time.Duration {
+	value := strings.TrimSpace(l.get(name))
+	if value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		l.problem("%s must be a positive duration (e.g., \"30s\"), got %q", name, value)
+		return def
+	}
+	return parsed
+}
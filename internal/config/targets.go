@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultTargetName is the name of the single Komodo target used when KOMODO_TARGETS is not set.
+const DefaultTargetName = "default"
+
+var targetEnvNameRegex = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// KomodoTarget holds the connection and filter settings of one Komodo instance.
+type KomodoTarget struct {
+	Name      string
+	Host      string
+	APIKey    string
+	APISecret string
+	Include   []string // Item patterns synced to this target; empty includes all items
+	Exclude   []string // Item patterns never synced to this target
+
+	// Orphaned variables deleted per run at most, 0 for no limit. Exceeding it
+	// skips the deletion phase, guarding against a wrong vault or filter.
+	MaxDeletions int
+}
+
+// targetEnvPrefix returns the environment variable prefix of a named target,
+// e.g. "KOMODO_STAGING_" for "staging".
+func targetEnvPrefix(name string) string {
+	return "KOMODO_" + strings.Trim(targetEnvNameRegex.ReplaceAllString(strings.ToUpper(name), "_"), "_") + "_"
+}
+
+// loadKomodoTargets reads the Komodo targets. Without KOMODO_TARGETS, a
+// single target is read from KOMODO_HOST, KOMODO_API_KEY, KOMODO_API_SECRET,
+// KOMODO_INCLUDE, KOMODO_EXCLUDE and KOMODO_MAX_DELETIONS. Otherwise each
+// listed target reads the same settings with its name inserted, e.g.
+// KOMODO_STAGING_HOST. Targets without a deletion limit use maxDeletions.
+func (l *loader) loadKomodoTargets(maxDeletions int) []KomodoTarget {
+	names := splitList(l.get("KOMODO_TARGETS"))
+	if len(names) == 0 {
+		return []KomodoTarget{l.loadKomodoTarget(DefaultTargetName, "KOMODO_", maxDeletions)}
+	}
+
+	targets := make([]KomodoTarget, 0, len(names))
+	seen := make(map[string]string)
+	for _, name := range names {
+		prefix := targetEnvPrefix(name)
+		if other, ok := seen[prefix]; ok {
+			l.problem("KOMODO_TARGETS entries %q and %q map to the same %s* variables", other, name, prefix)
+			continue
+		}
+		seen[prefix] = name
+		targets = append(targets, l.loadKomodoTarget(name, prefix, maxDeletions))
+	}
+	return targets
+}
+
+// loadKomodoTarget reads and validates a single target from variables with the given prefix.
+func (l *loader) loadKomodoTarget(name, prefix string, maxDeletions int) KomodoTarget {
+	target := KomodoTarget{
+		Name:         name,
+		Host:         l.get(prefix + "HOST"),
+		APIKey:       l.get(prefix + "API_KEY"),
+		APISecret:    l.get(prefix + "API_SECRET"),
+		Include:      splitList(l.get(prefix + "INCLUDE")),
+		Exclude:      splitList(l.get(prefix + "EXCLUDE")),
+		MaxDeletions: maxDeletions,
+	}
+	if strings.TrimSpace(l.get(prefix+"MAX_DELETIONS")) != "" {
+		target.MaxDeletions = l.nonNegativeInt(prefix + "MAX_DELETIONS")
+	}
+
+	fileKey := func(key string) string {
+		if name == DefaultTargetName && prefix == "KOMODO_" {
+			return key + " of targets"
+		}
+		return fmt.Sprintf("%s of the targets entry named %q", key, name)
+	}
+	if target.Host == "" {
+		l.notSet(prefix+"HOST", prefix+"HOST environment variable not set", fileKey("host"))
+	}
+	if target.APIKey == "" {
+		l.notSet(prefix+"API_KEY", prefix+"API_KEY environment variable not set", fileKey("api_key"))
+	}
+	if target.APISecret == "" {
+		l.notSet(prefix+"API_SECRET", prefix+"API_SECRET environment variable not set", fileKey("api_secret"))
+	}
+
+	// Ensure host starts with http:// or https:// and has no trailing slash
+	if target.Host != "" && !strings.HasPrefix(target.Host, "http") {
+		target.Host = "http://" + target.Host
+	}
+	target.Host = strings.TrimSuffix(target.Host, "/")
+
+	return target
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported values for WEBHOOK_FORMAT.
+const (
+	WebhookFormatJSON    = "json"
+	WebhookFormatSlack   = "slack"
+	WebhookFormatDiscord = "discord"
+	WebhookFormatNtfy    = "ntfy"
+)
+
+// Supported values for WEBHOOK_EVENTS.
+const (
+	EventChanges             = "changes"
+	EventErrors              = "errors"
+	EventDeletionThreshold   = "deletion-threshold"
+	EventConsecutiveFailures = "consecutive-failures"
+)
+
+// AllWebhookEvents lists every event, the default of WEBHOOK_EVENTS.
+var AllWebhookEvents = []string{EventChanges, EventErrors, EventDeletionThreshold, EventConsecutiveFailures}
+
+// DefaultWebhookFailureThreshold defines the default of WEBHOOK_CONSECUTIVE_FAILURES.
+const DefaultWebhookFailureThreshold = 3
+
+// Webhook is an outbound notification sink.
+type Webhook struct {
+	URL    string
+	Format string   // One of the WebhookFormat constants
+	Events []string // Events notified, see AllWebhookEvents
+}
+
+// loadWebhooks reads the webhook sink from WEBHOOK_URL, WEBHOOK_FORMAT and
+// WEBHOOK_EVENTS, or else the webhooks of the config file.
+func (l *loader) loadWebhooks() []Webhook {
+	url := strings.TrimSpace(l.get("WEBHOOK_URL"))
+	if url == "" {
+		if l.file == nil {
+			return nil
+		}
+		webhooks := l.file.webhooks
+		for i := range webhooks {
+			setting := func(key string) string { return fmt.Sprintf("webhooks[%d].%s", i, strings.ToLower(key)) }
+			if err := validateWebhook(&webhooks[i], setting); err != nil {
+				l.problem("%s: %v", l.file.path, err)
+			}
+		}
+		return webhooks
+	}
+	webhook := Webhook{
+		URL:    url,
+		Format: strings.ToLower(strings.TrimSpace(l.get("WEBHOOK_FORMAT"))),
+		Events: splitList(strings.ToLower(l.get("WEBHOOK_EVENTS"))),
+	}
+	setting := func(key string) string { return "WEBHOOK_" + key }
+	if err := validateWebhook(&webhook, setting); err != nil {
+		l.problems = append(l.problems, err)
+	}
+	return []Webhook{webhook}
+}
+
+// validateWebhook applies the defaults of a webhook and checks its format and
+// events. setting names the setting of a key in errors, e.g. "WEBHOOK_URL" for "URL".
+func validateWebhook(webhook *Webhook, setting func(key string) string) error {
+	if !strings.HasPrefix(webhook.URL, "http://") && !strings.HasPrefix(webhook.URL, "https://") {
+		return fmt.Errorf("%s must be an http:// or https:// URL", setting("URL"))
+	}
+	if webhook.Format == "" {
+		webhook.Format = WebhookFormatJSON
+	}
+	switch webhook.Format {
+	case WebhookFormatJSON, WebhookFormatSlack, WebhookFormatDiscord, WebhookFormatNtfy:
+	default:
+		return fmt.Errorf("%s must be one of %q, %q, %q or %q, got %q", setting("FORMAT"),
+			WebhookFormatJSON, WebhookFormatSlack, WebhookFormatDiscord, WebhookFormatNtfy, webhook.Format)
+	}
+	if len(webhook.Events) == 0 {
+		webhook.Events = AllWebhookEvents
+	}
+	for _, event := range webhook.Events {
+		if !containsString(AllWebhookEvents, event) {
+			return fmt.Errorf("%s entry %q must be one of %s", setting("EVENTS"), event, strings.Join(AllWebhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
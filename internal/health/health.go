@@ -0,0 +1,135 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	mu              sync.Mutex
+	loopRunning     bool
+	lastHeartbeat   time.Time
+	heartbeatMaxAge time.Duration
+	lastRun         time.Time
+	lastSuccess     time.Time
+	lastErrors      int
+)
+
+// SetLoopRunning records whether the daemon's sync loop is running.
+func SetLoopRunning(running bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	loopRunning = running
+}
+
+// Heartbeat records an iteration of the daemon's sync loop. The loop counts as
+// stuck once no further heartbeat is recorded within maxAge.
+func Heartbeat(maxAge time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	lastHeartbeat = time.Now()
+	heartbeatMaxAge = maxAge
+}
+
+// RecordRun records the outcome of a synchronization run.
+func RecordRun(finishedAt time.Time, errors int) {
+	mu.Lock()
+	defer mu.Unlock()
+	lastRun = finishedAt
+	lastErrors = errors
+	if errors == 0 {
+		lastSuccess = finishedAt
+	}
+}
+
+// livenessResponse is the body returned by the liveness endpoint.
+type livenessResponse struct {
+	Alive         bool       `json:"alive"`
+	LoopRunning   bool       `json:"loop_running"`
+	LastHeartbeat *time.Time `json:"last_heartbeat,omitempty"`
+}
+
+// readinessResponse is the body returned by the readiness endpoint.
+type readinessResponse struct {
+	Ready       bool              `json:"ready"`
+	LastRun     *time.Time        `json:"last_run,omitempty"`
+	LastSuccess *time.Time        `json:"last_success,omitempty"`
+	LastErrors  int               `json:"last_errors"`
+	Checks      map[string]string `json:"checks"`
+}
+
+// LivenessHandler reports whether the process is alive and its sync loop is
+// running, that is it recorded a heartbeat recently enough.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		response := livenessResponse{Alive: true, LoopRunning: loopRunning}
+		if !lastHeartbeat.IsZero() {
+			heartbeatAt := lastHeartbeat
+			response.LastHeartbeat = &heartbeatAt
+			if time.Since(lastHeartbeat) > heartbeatMaxAge {
+				response.LoopRunning = false // Stuck, e.g. deadlocked
+			}
+		}
+		mu.Unlock()
+
+		status := http.StatusOK
+		if !response.LoopRunning {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, response)
+	})
+}
+
+// ReadinessHandler reports whether the last successful sync finished within
+// the age returned by maxAge and whether every dependency is reachable. check
+// returns the error of each dependency by name, nil when it is reachable.
+func ReadinessHandler(maxAge func() time.Duration, check func() map[string]error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		response := readinessResponse{LastErrors: lastErrors, Checks: make(map[string]string)}
+		if !lastRun.IsZero() {
+			runAt := lastRun
+			response.LastRun = &runAt
+		}
+		if !lastSuccess.IsZero() {
+			successAt := lastSuccess
+			response.LastSuccess = &successAt
+		}
+		mu.Unlock()
+
+		response.Ready = true
+		if response.LastSuccess == nil {
+			response.Checks["sync"] = "no successful sync yet"
+			response.Ready = false
+		} else if age := time.Since(*response.LastSuccess); age > maxAge() {
+			response.Checks["sync"] = "last successful sync was " + age.Round(time.Second).String() + " ago"
+			response.Ready = false
+		} else {
+			response.Checks["sync"] = "ok"
+		}
+
+		for name, err := range check() {
+			if err != nil {
+				response.Checks[name] = err.Error()
+				response.Ready = false
+			} else {
+				response.Checks[name] = "ok"
+			}
+		}
+
+		status := http.StatusOK
+		if !response.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, response)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
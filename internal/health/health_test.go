@@ -0,0 +1,32 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLivenessHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		running bool
+		maxAge  time.Duration
+		want    int
+	}{
+		{"recent heartbeat", true, time.Hour, http.StatusOK},
+		{"stale heartbeat", true, -time.Second, http.StatusServiceUnavailable},
+		{"loop stopped", false, time.Hour, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLoopRunning(tt.running)
+			Heartbeat(tt.maxAge)
+			recorder := httptest.NewRecorder()
+			LivenessHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+}
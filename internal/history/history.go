@@ -0,0 +1,114 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"komodo-op/internal/synchronizer"
+)
+
+// fileName is the name of the history file in the state directory.
+const fileName = "history.json"
+
+// Store keeps the reports of the last runs in a JSON file, newest last.
+type Store struct {
+	path string
+	size int // Reports kept
+	mu   sync.Mutex
+}
+
+// NewStore creates a store keeping the last size reports in dir.
+func NewStore(dir string, size int) *Store {
+	return &Store{path: filepath.Join(dir, fileName), size: size}
+}
+
+// Path returns the path of the history file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Append adds the report of a finished run, dropping the oldest reports beyond the store size.
+func (s *Store) Append(report *synchronizer.Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reports, err := s.read()
+	if err != nil {
+		return err
+	}
+	reports = append(reports, report)
+	if len(reports) > s.size {
+		reports = reports[len(reports)-s.size:]
+	}
+	return s.write(reports)
+}
+
+// List returns the stored reports, oldest first.
+func (s *Store) List() ([]*synchronizer.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Find returns the stored report of a run. Returns nil if it is not (or no longer) stored.
+func (s *Store) Find(runID string) (*synchronizer.Report, error) {
+	reports, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, report := range reports {
+		if report.RunID == runID {
+			return report, nil
+		}
+	}
+	return nil, nil
+}
+
+// read loads the history file. A missing file is an empty history. Callers hold mu.
+func (s *Store) read() ([]*synchronizer.Report, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history %s: %w", s.path, err)
+	}
+	var reports []*synchronizer.Report
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, fmt.Errorf("failed to decode history %s: %w", s.path, err)
+	}
+	return reports, nil
+}
+
+// write replaces the history file atomically, so a crash never leaves it truncated. Callers hold mu.
+func (s *Store) write(reports []*synchronizer.Report) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), fileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package komodoclient
+
+import (
+	"fmt"
+
+	"komodo-op/internal/logging"
+)
+
+// AccountType identifies a kind of Komodo credential account.
+type AccountType string
+
+const (
+	// AccountGitProvider is a git provider account used to clone private repos.
+	AccountGitProvider AccountType = "GitProviderAccount"
+	// AccountDockerRegistry is a Docker registry account used to push and pull images.
+	AccountDockerRegistry AccountType = "DockerRegistryAccount"
+)
+
+// MongoID is the JSON representation of a Komodo database ID.
+type MongoID struct {
+	OID string `json:"$oid"`
+}
+
+// Account defines the structure of an account returned by the Komodo API.
+type Account struct {
+	ID       MongoID `json:"_id"`
+	Domain   string  `json:"domain"`
+	HTTPS    bool    `json:"https"` // Git provider accounts only
+	Username string  `json:"username"`
+	Token    string  `json:"token"` // May be empty if not permitted to read it
+}
+
+// AccountParams defines the account fields sent on create and update.
+type AccountParams struct {
+	Domain   string `json:"domain"`
+	HTTPS    *bool  `json:"https,omitempty"` // Git provider accounts only
+	Username string `json:"username"`
+	Token    string `json:"token"`
+}
+
+// CreateAccountParams defines parameters for the Create*Account requests.
+type CreateAccountParams struct {
+	Account AccountParams `json:"account"`
+}
+
+// UpdateAccountParams defines parameters for the Update*Account requests.
+type UpdateAccountParams struct {
+	ID      string        `json:"id"`
+	Account AccountParams `json:"account"`
+}
+
+// DeleteAccountParams defines parameters for the Delete*Account requests.
+type DeleteAccountParams struct {
+	ID string `json:"id"`
+}
+
+// ListAccounts lists all accounts of the given type.
+func (c *Client) ListAccounts(kind AccountType) ([]Account, error) {
+	payload := Request{
+		Type:   fmt.Sprintf("List%ss", kind),
+		Params: map[string]interface{}{},
+	}
+	var response []Account
+	_, _, err := c.makeRequest("/read", payload, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Komodo %ss: %w", kind, err)
+	}
+	logging.Debug("Successfully listed %d %ss from Komodo", len(response), kind)
+	return response, nil
+}
+
+// CreateAccount creates a new account of the given type.
+func (c *Client) CreateAccount(kind AccountType, account AccountParams) error {
+	payload := Request{
+		Type:   fmt.Sprintf("Create%s", kind),
+		Params: CreateAccountParams{Account: account},
+	}
+	_, _, err := c.makeRequest("/write", payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Komodo %s '%s@%s': %w", kind, account.Username, account.Domain, err)
+	}
+	logging.Info("    Successfully created Komodo %s: %s@%s", kind, account.Username, account.Domain)
+	return nil
+}
+
+// UpdateAccount updates an existing account of the given type.
+func (c *Client) UpdateAccount(kind AccountType, id string, account AccountParams) error {
+	payload := Request{
+		Type:   fmt.Sprintf("Update%s", kind),
+		Params: UpdateAccountParams{ID: id, Account: account},
+	}
+	_, _, err := c.makeRequest("/write", payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update Komodo %s '%s@%s': %w", kind, account.Username, account.Domain, err)
+	}
+	logging.Info("    Successfully updated Komodo %s: %s@%s", kind, account.Username, account.Domain)
+	return nil
+}
+
+// DeleteAccount deletes an account of the given type by ID.
+func (c *Client) DeleteAccount(kind AccountType, id string) error {
+	payload := Request{
+		Type:   fmt.Sprintf("Delete%s", kind),
+		Params: DeleteAccountParams{ID: id},
+	}
+	_, _, err := c.makeRequest("/write", payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete Komodo %s '%s': %w", kind, id, err)
+	}
+	logging.Info("    Successfully deleted Komodo %s: %s", kind, id)
+	return nil
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"komodo-op/internal/config"
 	"komodo-op/internal/logging"
+	"komodo-op/internal/metrics"
+	"komodo-op/internal/tracing"
 	"komodo-op/internal/util"
 )
 
@@ -32,6 +35,18 @@ type UpdateVariableValueParams struct {
 	Value string `json:"value"`
 }
 
+// UpdateVariableDescriptionParams defines parameters for the UpdateVariableDescription request.
+type UpdateVariableDescriptionParams struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// UpdateVariableIsSecretParams defines parameters for the UpdateVariableIsSecret request.
+type UpdateVariableIsSecretParams struct {
+	Name     string `json:"name"`
+	IsSecret bool   `json:"is_secret"`
+}
+
 // DeleteVariableParams defines parameters for the DeleteVariable request.
 type DeleteVariableParams struct {
 	Name string `json:"name"`
@@ -64,23 +79,36 @@ type ErrorResponse struct {
 
 // --- Komodo Client ---
 
-// Client manages communication with the Komodo API.
+// Client manages communication with the API of one Komodo instance.
 type Client struct {
 	httpClient *http.Client
-	cfg        *config.Config
+	target     config.KomodoTarget
+	span       *tracing.Span // Parent of the spans of every request, nil when not tracing
 }
 
-// NewClient creates a new Komodo API client.
-func NewClient(httpClient *http.Client, cfg *config.Config) *Client {
+// NewClient creates a new Komodo API client for a target.
+func NewClient(httpClient *http.Client, target config.KomodoTarget) *Client {
 	return &Client{
 		httpClient: httpClient,
-		cfg:        cfg,
+		target:     target,
 	}
 }
 
+// Target returns the Komodo target this client talks to.
+func (c *Client) Target() config.KomodoTarget {
+	return c.target
+}
+
+// WithSpan returns a copy of the client tracing its requests as children of span.
+func (c *Client) WithSpan(span *tracing.Span) *Client {
+	clone := *c
+	clone.span = span
+	return &clone
+}
+
 // makeRequest executes a request against the Komodo API.
 func (c *Client) makeRequest(path string, payload interface{}, target interface{}) (int, []byte, error) {
-	url := fmt.Sprintf("%s%s", c.cfg.KomodoHost, path) // path should start with / (e.g., /read, /write)
+	url := fmt.Sprintf("%s%s", c.target.Host, path) // path should start with / (e.g., /read, /write)
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -88,7 +116,7 @@ func (c *Client) makeRequest(path string, payload interface{}, target interface{
 	}
 
 	logging.Debug("Komodo Request URL: POST %s", url)
-	logging.Debug("Komodo Request Body: %s", string(payloadBytes))
+	logging.Trace("Komodo Request Body: %s", string(payloadBytes))
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payloadBytes))
 	if err != nil {
@@ -96,31 +124,47 @@ func (c *Client) makeRequest(path string, payload interface{}, target interface{
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Api-Key", c.cfg.KomodoAPIKey)
-	req.Header.Set("X-Api-Secret", c.cfg.KomodoAPISecret)
+	req.Header.Set("X-Api-Key", c.target.APIKey)
+	req.Header.Set("X-Api-Secret", c.target.APISecret)
 	req.Header.Set("Accept", "application/json")
 
+	endpoint := path
+	if request, ok := payload.(Request); ok {
+		endpoint = path + "/" + request.Type
+	}
+
+	span := c.span.StartClient("komodo "+endpoint, "komodo.target", c.target.Name, "http.request.method", "POST", "url.full", url)
+	defer span.End()
+
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		metrics.RecordRequest("komodo", endpoint, 0, time.Since(start), err)
+		span.SetError(err)
 		return 0, nil, fmt.Errorf("failed to execute Komodo request to %s: %w", url, err)
 	}
 	defer resp.Body.Close()
+	metrics.RecordRequest("komodo", endpoint, resp.StatusCode, time.Since(start), nil)
+	span.SetAttributes("http.response.status_code", strconv.Itoa(resp.StatusCode))
 
 	bodyBytes, readErr := util.ReadAll(resp.Body) // Read body regardless of status code
 	if readErr != nil {
 		logging.Error("Failed to read Komodo response body from %s: %v", url, readErr)
+		span.SetError(readErr)
 		return resp.StatusCode, nil, fmt.Errorf("Komodo API request to %s returned status %s, but failed to read response body: %w", url, resp.Status, readErr)
 	}
 
 	logging.Debug("Komodo Response Status: %s", resp.Status)
-	logging.Debug("Komodo Response Body: %s", string(bodyBytes))
+	logging.Trace("Komodo Response Body: %s", string(bodyBytes))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("Komodo API request to %s failed with status %s", url, resp.Status)
 		var komodoErr ErrorResponse
 		if json.Unmarshal(bodyBytes, &komodoErr) == nil && komodoErr.Error != "" {
-			return resp.StatusCode, bodyBytes, fmt.Errorf("Komodo API request to %s failed with status %s: %s (Trace: %v)", url, resp.Status, komodoErr.Error, komodoErr.Trace)
+			err = fmt.Errorf("Komodo API request to %s failed with status %s: %s (Trace: %v)", url, resp.Status, komodoErr.Error, komodoErr.Trace)
 		}
-		return resp.StatusCode, bodyBytes, fmt.Errorf("Komodo API request to %s failed with status %s", url, resp.Status)
+		span.SetError(err)
+		return resp.StatusCode, bodyBytes, err
 	}
 
 	if target != nil {
@@ -164,21 +208,21 @@ func (c *Client) GetVariable(name string) (*VariableResponse, bool, error) {
 }
 
 // CreateVariable creates a new Komodo variable.
-func (c *Client) CreateVariable(name, value, description string) error {
+func (c *Client) CreateVariable(name, value, description string, isSecret bool) error {
 	payload := Request{
 		Type: "CreateVariable",
 		Params: CreateParams{
 			Name:        name,
 			Value:       value,
 			Description: description,
-			IsSecret:    true,
+			IsSecret:    isSecret,
 		},
 	}
 	_, _, err := c.makeRequest("/write", payload, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create Komodo variable '%s': %w", name, err)
 	}
-	logging.Info("    Successfully created Komodo secret: %s", name)
+	logging.Info("    Successfully created Komodo variable: %s (secret: %t)", name, isSecret)
 	return nil
 }
 
@@ -199,6 +243,40 @@ func (c *Client) UpdateVariableValue(name, value string) error {
 	return nil
 }
 
+// UpdateVariableDescription updates the description of an existing Komodo variable.
+func (c *Client) UpdateVariableDescription(name, description string) error {
+	payload := Request{
+		Type: "UpdateVariableDescription",
+		Params: UpdateVariableDescriptionParams{
+			Name:        name,
+			Description: description,
+		},
+	}
+	_, _, err := c.makeRequest("/write", payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update description of Komodo variable '%s': %w", name, err)
+	}
+	logging.Info("    Successfully updated description of Komodo secret: %s", name)
+	return nil
+}
+
+// UpdateVariableIsSecret updates whether an existing Komodo variable is marked as secret.
+func (c *Client) UpdateVariableIsSecret(name string, isSecret bool) error {
+	payload := Request{
+		Type: "UpdateVariableIsSecret",
+		Params: UpdateVariableIsSecretParams{
+			Name:     name,
+			IsSecret: isSecret,
+		},
+	}
+	_, _, err := c.makeRequest("/write", payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update is_secret of Komodo variable '%s': %w", name, err)
+	}
+	logging.Info("    Successfully updated is_secret of Komodo secret: %s", name)
+	return nil
+}
+
 // DeleteVariable deletes a Komodo variable by name.
 func (c *Client) DeleteVariable(name string) error {
 	payload := Request{
@@ -242,3 +320,13 @@ func (c *Client) ListVariables() (map[string]VariableResponse, error) {
 	logging.Info("Successfully listed %d variables from Komodo", len(varsMap))
 	return varsMap, nil
 }
+
+// Ping checks that the Komodo API is reachable and accepts the configured credentials.
+func (c *Client) Ping() error {
+	payload := Request{
+		Type:   "GetVersion",
+		Params: map[string]interface{}{},
+	}
+	_, _, err := c.makeRequest("/read", payload, nil)
+	return err
+}
@@ -0,0 +1,87 @@
+package komodoclient
+
+import (
+	"fmt"
+
+	"komodo-op/internal/logging"
+)
+
+// DeployStackParams defines parameters for the DeployStack execution.
+type DeployStackParams struct {
+	Stack string `json:"stack"`
+}
+
+// DeployParams defines parameters for the Deploy execution.
+type DeployParams struct {
+	Deployment string `json:"deployment"`
+}
+
+// RunProcedureParams defines parameters for the RunProcedure execution.
+type RunProcedureParams struct {
+	Procedure string `json:"procedure"`
+}
+
+// RunActionParams defines parameters for the RunAction execution.
+type RunActionParams struct {
+	Action string                 `json:"action"`
+	Args   map[string]interface{} `json:"args,omitempty"`
+}
+
+// execute submits an execution to the Komodo API. Komodo runs executions
+// asynchronously, so success only means the execution was accepted.
+func (c *Client) execute(payload Request) error {
+	_, _, err := c.makeRequest("/execute", payload, nil)
+	return err
+}
+
+// DeployStack triggers a deploy of a Komodo Stack.
+func (c *Client) DeployStack(name string) error {
+	err := c.execute(Request{
+		Type:   "DeployStack",
+		Params: DeployStackParams{Stack: name},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deploy Komodo stack '%s': %w", name, err)
+	}
+	logging.Info("    Successfully triggered deploy of Komodo stack: %s", name)
+	return nil
+}
+
+// Deploy triggers a deploy of a Komodo Deployment.
+func (c *Client) Deploy(name string) error {
+	err := c.execute(Request{
+		Type:   "Deploy",
+		Params: DeployParams{Deployment: name},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deploy Komodo deployment '%s': %w", name, err)
+	}
+	logging.Info("    Successfully triggered deploy of Komodo deployment: %s", name)
+	return nil
+}
+
+// RunProcedure triggers a run of a Komodo Procedure.
+func (c *Client) RunProcedure(name string) error {
+	err := c.execute(Request{
+		Type:   "RunProcedure",
+		Params: RunProcedureParams{Procedure: name},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run Komodo procedure '%s': %w", name, err)
+	}
+	logging.Info("    Successfully triggered Komodo procedure: %s", name)
+	return nil
+}
+
+// RunAction triggers a run of a Komodo Action, passing args to its script.
+func (c *Client) RunAction(name string, args map[string]interface{}) error {
+	err := c.execute(Request{
+		Type:   "RunAction",
+		Params: RunActionParams{Action: name, Args: args},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run Komodo action '%s': %w", name, err)
+	}
+	logging.Info("    Successfully triggered Komodo action: %s", name)
+	return nil
+}
@@ -0,0 +1,106 @@
+package komodoclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"komodo-op/internal/logging"
+)
+
+// ResourceType identifies a Komodo resource kind that carries its own environment block.
+type ResourceType string
+
+const (
+	// ResourceStack is a Komodo Stack.
+	ResourceStack ResourceType = "Stack"
+	// ResourceDeployment is a Komodo Deployment.
+	ResourceDeployment ResourceType = "Deployment"
+	// ResourceRepo is a Komodo Repo.
+	ResourceRepo ResourceType = "Repo"
+	// ResourceBuild is a Komodo Build.
+	ResourceBuild ResourceType = "Build"
+)
+
+// paramKey returns the parameter name Komodo uses to reference a resource of this type.
+func (t ResourceType) paramKey() string {
+	return strings.ToLower(string(t))
+}
+
+// ResourceConfig holds the parts of a resource's config komodo-op reads.
+type ResourceConfig struct {
+	Environment string `json:"environment"`
+}
+
+// Plural returns the lowercase plural name of the resource type, e.g. "stacks".
+func (t ResourceType) Plural() string {
+	return t.paramKey() + "s"
+}
+
+// Resource defines the subset of a Komodo resource returned by the ListFull* requests.
+type Resource struct {
+	Name      string          `json:"name"`
+	Config    ResourceConfig  `json:"-"`
+	RawConfig json.RawMessage `json:"config"` // Full config, used to scan for variable references
+}
+
+// UpdateResourceParams defines parameters for the Update* resource requests.
+type UpdateResourceParams struct {
+	ID     string         `json:"id"` // Komodo accepts the resource name here as well
+	Config ResourceConfig `json:"config"`
+}
+
+// ListResources lists all resources of the given type, including their config.
+func (c *Client) ListResources(kind ResourceType) ([]Resource, error) {
+	payload := Request{
+		Type:   fmt.Sprintf("ListFull%ss", kind),
+		Params: map[string]interface{}{"query": map[string]interface{}{}},
+	}
+	var response []Resource
+	_, _, err := c.makeRequest("/read", payload, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Komodo %s: %w", kind.Plural(), err)
+	}
+	for i := range response {
+		if len(response[i].RawConfig) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(response[i].RawConfig, &response[i].Config); err != nil {
+			return nil, fmt.Errorf("failed to decode config of Komodo %s '%s': %w", kind.paramKey(), response[i].Name, err)
+		}
+	}
+	logging.Debug("Successfully listed %d %s from Komodo", len(response), kind.Plural())
+	return response, nil
+}
+
+// UpdateResourceEnvironment replaces the environment block of a resource.
+func (c *Client) UpdateResourceEnvironment(kind ResourceType, name, environment string) error {
+	payload := Request{
+		Type: fmt.Sprintf("Update%s", kind),
+		Params: UpdateResourceParams{
+			ID:     name,
+			Config: ResourceConfig{Environment: environment},
+		},
+	}
+	_, _, err := c.makeRequest("/write", payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update environment of Komodo %s '%s': %w", kind.paramKey(), name, err)
+	}
+	logging.Info("    Successfully updated environment of Komodo %s: %s", kind.paramKey(), name)
+	return nil
+}
+
+// ListSecrets lists the names of the secrets defined in the Komodo core config.
+// These can be interpolated like variables but are not returned by ListVariables.
+func (c *Client) ListSecrets() ([]string, error) {
+	payload := Request{
+		Type:   "ListSecrets",
+		Params: map[string]interface{}{},
+	}
+	var response []string
+	_, _, err := c.makeRequest("/read", payload, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Komodo core secrets: %w", err)
+	}
+	return response, nil
+}
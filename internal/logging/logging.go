@@ -1,8 +1,13 @@
 package logging
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
 	"strings"
+	"sync"
 )
 
 // LogLevel defines the level of logging.
@@ -11,43 +16,124 @@ type LogLevel int
 const (
 	// LogLevelError logs only errors.
 	LogLevelError LogLevel = iota
-	// LogLevelInfo logs info and errors.
+	// LogLevelWarn logs warnings and errors.
+	LogLevelWarn
+	// LogLevelInfo logs info, warnings and errors.
 	LogLevelInfo
-	// LogLevelDebug logs debug, info, and errors.
+	// LogLevelDebug logs debug, info, warnings and errors.
 	LogLevelDebug
+	// LogLevelTrace logs everything, including request and response details.
+	LogLevelTrace
 )
 
-var currentLogLevel = LogLevelInfo // Default level
+// slogLevelTrace is the slog level used for TRACE messages, below slog.LevelDebug.
+const slogLevelTrace = slog.LevelDebug - 4
+
+// Supported values for LOG_FORMAT.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	mu       sync.RWMutex
+	levelVar           = new(slog.LevelVar) // Level shared by all handlers, INFO by default
+	output   io.Writer = os.Stderr
+	logger             = newLogger(FormatText)
+)
+
+// newLogger creates a slog logger writing in the given format.
+func newLogger(format string) *slog.Logger {
+	options := &slog.HandlerOptions{
+		Level:       levelVar,
+		ReplaceAttr: replaceAttr,
+	}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(output, options))
+	}
+	return slog.New(slog.NewTextHandler(output, options))
+}
+
+// replaceAttr renders the custom TRACE level by name and redacts secrets from
+// the message and every other attribute.
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 {
+		switch a.Key {
+		case slog.LevelKey:
+			if level, ok := a.Value.Any().(slog.Level); ok && level <= slogLevelTrace {
+				return slog.String(slog.LevelKey, "TRACE")
+			}
+			return a
+		case slog.TimeKey:
+			return a
+		}
+	}
+	return redactAttr(a)
+}
+
+// SetFormat selects the output format, "text" (default) or "json".
+func SetFormat(format string) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = FormatText
+	}
+	invalid := format != FormatText && format != FormatJSON
+	if invalid {
+		format = FormatText
+	}
+
+	mu.Lock()
+	logger = newLogger(format)
+	mu.Unlock()
+
+	if invalid {
+		Warn("Invalid LOG_FORMAT. Defaulting to %s.", FormatText)
+	}
+}
 
 // SetLevel sets the global log level based on a string identifier.
 func SetLevel(levelStr string) {
+	var level LogLevel
+	invalid := false
 	switch strings.ToUpper(levelStr) {
+	case "TRACE":
+		level = LogLevelTrace
 	case "DEBUG":
-		currentLogLevel = LogLevelDebug
+		level = LogLevelDebug
 	case "INFO":
-		currentLogLevel = LogLevelInfo
+		level = LogLevelInfo
+	case "WARN", "WARNING":
+		level = LogLevelWarn
 	case "ERROR":
-		currentLogLevel = LogLevelError
+		level = LogLevelError
 	default:
-		// Log warning only if levelStr is not empty
-		if levelStr != "" {
-			log.Printf("Warning: Invalid LOG_LEVEL '%s'. Defaulting to INFO.", levelStr)
-		}
-		currentLogLevel = LogLevelInfo
+		// Warn only if levelStr is not empty
+		invalid = levelStr != ""
+		level = LogLevelInfo
+	}
+
+	levelVar.Set(toSlogLevel(level))
+
+	if invalid {
+		Warn("Invalid LOG_LEVEL '%s'. Defaulting to INFO.", levelStr)
 	}
 	// Print log level setting only if not default due to empty input
-	if levelStr != "" || currentLogLevel != LogLevelInfo {
-		log.Printf("Log level set to: %s", LevelToString(currentLogLevel))
+	if levelStr != "" || level != LogLevelInfo {
+		Info("Log level set to: %s", LevelToString(level))
 	}
 }
 
 // LevelToString converts a LogLevel to its string representation.
 func LevelToString(level LogLevel) string {
 	switch level {
+	case LogLevelTrace:
+		return "TRACE"
 	case LogLevelDebug:
 		return "DEBUG"
 	case LogLevelInfo:
 		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
 	case LogLevelError:
 		return "ERROR"
 	default:
@@ -55,23 +141,84 @@ func LevelToString(level LogLevel) string {
 	}
 }
 
-// Debug logs a message at the DEBUG level.
-func Debug(format string, v ...interface{}) {
-	if currentLogLevel >= LogLevelDebug {
-		log.Printf("[DEBUG] "+format, v...)
+// toSlogLevel converts a LogLevel to the slog level enabling it.
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelTrace:
+		return slogLevelTrace
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
 	}
 }
 
-// Info logs a message at the INFO level.
-func Info(format string, v ...interface{}) {
-	if currentLogLevel >= LogLevelInfo {
-		log.Printf("[INFO] "+format, v...)
-	}
+// Logger logs messages carrying a fixed set of structured attributes,
+// e.g. run_id, phase, variable, item_id or op.
+type Logger struct {
+	attrs []any
 }
 
-// Error logs a message at the ERROR level.
-func Error(format string, v ...interface{}) {
-	if currentLogLevel >= LogLevelError {
-		log.Printf("[ERROR] "+format, v...)
+// With returns a logger adding the given key-value pairs to every message.
+func With(args ...any) *Logger {
+	return &Logger{attrs: args}
+}
+
+// With returns a logger adding the given key-value pairs to those of l.
+func (l *Logger) With(args ...any) *Logger {
+	attrs := make([]any, 0, len(l.attrs)+len(args))
+	attrs = append(attrs, l.attrs...)
+	attrs = append(attrs, args...)
+	return &Logger{attrs: attrs}
+}
+
+// log formats and emits a message if its level is enabled.
+func (l *Logger) log(level slog.Level, format string, v ...interface{}) {
+	if level < levelVar.Level() {
+		return
 	}
+	mu.RLock()
+	current := logger
+	mu.RUnlock()
+
+	// Call sites indent messages to show nesting, structured attributes replace that
+	msg := strings.TrimLeft(fmt.Sprintf(format, v...), " ")
+	current.Log(context.Background(), level, msg, l.attrs...)
 }
+
+// Trace logs a message at the TRACE level.
+func (l *Logger) Trace(format string, v ...interface{}) { l.log(slogLevelTrace, format, v...) }
+
+// Debug logs a message at the DEBUG level.
+func (l *Logger) Debug(format string, v ...interface{}) { l.log(slog.LevelDebug, format, v...) }
+
+// Info logs a message at the INFO level.
+func (l *Logger) Info(format string, v ...interface{}) { l.log(slog.LevelInfo, format, v...) }
+
+// Warn logs a message at the WARN level.
+func (l *Logger) Warn(format string, v ...interface{}) { l.log(slog.LevelWarn, format, v...) }
+
+// Error logs a message at the ERROR level.
+func (l *Logger) Error(format string, v ...interface{}) { l.log(slog.LevelError, format, v...) }
+
+// root logs without additional attributes.
+var root = &Logger{}
+
+// Trace logs a message at the TRACE level.
+func Trace(format string, v ...interface{}) { root.log(slogLevelTrace, format, v...) }
+
+// Debug logs a message at the DEBUG level.
+func Debug(format string, v ...interface{}) { root.log(slog.LevelDebug, format, v...) }
+
+// Info logs a message at the INFO level.
+func Info(format string, v ...interface{}) { root.log(slog.LevelInfo, format, v...) }
+
+// Warn logs a message at the WARN level.
+func Warn(format string, v ...interface{}) { root.log(slog.LevelWarn, format, v...) }
+
+// Error logs a message at the ERROR level.
+func Error(format string, v ...interface{}) { root.log(slog.LevelError, format, v...) }
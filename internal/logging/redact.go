@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedPlaceholder replaces every scrubbed value in log output.
+const redactedPlaceholder = "[REDACTED]"
+
+// minSecretLength is the length below which registered values are ignored,
+// as scrubbing very short strings would mangle unrelated log output.
+const minSecretLength = 4
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = make(map[string]bool)
+	// secretReplacer scrubs all registered values, rebuilt on registration.
+	secretReplacer = strings.NewReplacer()
+)
+
+var (
+	// Authorization and X-Api-Secret headers, as dumped by net/http, in JSON or as
+	// key=value pairs. Values may be wrapped in a list, as http.Header prints them.
+	headerPattern = regexp.MustCompile(`(?i)((?:authorization|x-api-secret)["']?\s*[:=]\s*\[?["']?(?:(?:bearer|basic)\s+)?)[^\s"',}\]]+`)
+	// JSON "value" fields, e.g. in Komodo variable requests and responses.
+	valuePattern = regexp.MustCompile(`("value"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// RegisterSecret adds values to the set scrubbed from every log line.
+// Empty and very short values are ignored.
+func RegisterSecret(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	added := false
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) < minSecretLength || secrets[value] {
+			continue
+		}
+		secrets[value] = true
+		added = true
+	}
+	if added {
+		secretReplacer = buildSecretReplacer()
+	}
+}
+
+// buildSecretReplacer returns a replacer scrubbing every registered value, both
+// raw and JSON-escaped. Callers hold secretsMu.
+func buildSecretReplacer() *strings.Replacer {
+	var forms []string
+	for value := range secrets {
+		forms = append(forms, value)
+		if escaped, err := json.Marshal(value); err == nil {
+			if form := string(escaped[1 : len(escaped)-1]); form != value {
+				forms = append(forms, form)
+			}
+		}
+	}
+	// Longest first, so a value containing another one is replaced as a whole
+	sort.Slice(forms, func(i, j int) bool { return len(forms[i]) > len(forms[j]) })
+
+	pairs := make([]string, 0, 2*len(forms))
+	for _, form := range forms {
+		pairs = append(pairs, form, redactedPlaceholder)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// Redact scrubs registered secret values, credential headers and JSON "value"
+// fields from s.
+func Redact(s string) string {
+	secretsMu.RLock()
+	replacer := secretReplacer
+	secretsMu.RUnlock()
+
+	s = replacer.Replace(s)
+	s = headerPattern.ReplaceAllString(s, "${1}"+redactedPlaceholder)
+	s = valuePattern.ReplaceAllString(s, `${1}"`+redactedPlaceholder+`"`)
+	return s
+}
+
+// redactAttr scrubs the message and every string-like attribute value of a log record.
+func redactAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		switch value := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, Redact(value.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, Redact(value.String()))
+		case []byte:
+			return slog.String(a.Key, Redact(string(value)))
+		}
+	}
+	return a
+}
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	RegisterSecret("hunter2-secret", `pa"ss\word`, "abc", "  spaced-secret  ", "outer-secret-value", "secret-value")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"registered secret", "password is hunter2-secret.", "password is [REDACTED]."},
+		{"every occurrence", "hunter2-secret hunter2-secret", "[REDACTED] [REDACTED]"},
+		{"JSON-escaped secret", `{"token":"pa\"ss\\word"}`, `{"token":"[REDACTED]"}`},
+		{"raw secret with quotes", `token pa"ss\word`, "token [REDACTED]"},
+		{"trimmed secret", "x spaced-secret y", "x [REDACTED] y"},
+		{"longest secret first", "outer-secret-value", "[REDACTED]"},
+		{"short values are not registered", "abc", "abc"},
+		{"bearer authorization header", "Authorization: Bearer eyJhbGciOi.x.y", "Authorization: Bearer [REDACTED]"},
+		{"basic authorization header", "authorization=Basic dXNlcjpwYXNz", "authorization=Basic [REDACTED]"},
+		{"API secret header", "X-Api-Secret: s3cr3t", "X-Api-Secret: [REDACTED]"},
+		{"API secret header in JSON", `{"X-Api-Secret":"s3cr3t","X-Api-Key":"k"}`, `{"X-Api-Secret":"[REDACTED]","X-Api-Key":"k"}`},
+		{"API secret header dumped by net/http", "map[X-Api-Secret:[s3cr3t]]", "map[X-Api-Secret:[[REDACTED]]]"},
+		{"API secret header as JSON array", `{"X-Api-Secret":["s3cr3t"]}`, `{"X-Api-Secret":["[REDACTED]"]}`},
+		{"value field", `{"name":"DB","value":"p@ss"}`, `{"name":"DB","value":"[REDACTED]"}`},
+		{"value field with escapes", `{"value" : "a\"b\\", "is_secret":true}`, `{"value" : "[REDACTED]", "is_secret":true}`},
+		{"empty value field", `{"value":""}`, `{"value":"[REDACTED]"}`},
+		{"unrelated text", "Synced 3 variables", "Synced 3 variables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
+
+func TestRedactAttr(t *testing.T) {
+	RegisterSecret("attr-secret")
+
+	tests := []struct {
+		name string
+		attr slog.Attr
+		want slog.Value
+	}{
+		{"string", slog.String("k", "is attr-secret"), slog.StringValue("is [REDACTED]")},
+		{"error", slog.Any("err", errors.New("bad attr-secret")), slog.StringValue("bad [REDACTED]")},
+		{"stringer", slog.Any("k", stringer("attr-secret")), slog.StringValue("[REDACTED]")},
+		{"bytes", slog.Any("body", []byte(`{"value":"x"}`)), slog.StringValue(`{"value":"[REDACTED]"}`)},
+		{"integer", slog.Int("n", 3), slog.IntValue(3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactAttr(tt.attr)
+			if got.Key != tt.attr.Key || !got.Value.Equal(tt.want) {
+				t.Errorf("redactAttr() = %v, want %s=%v", got, tt.attr.Key, tt.want)
+			}
+		})
+	}
+}
+
+// captureLogs sends the log output in the given format at TRACE level to the
+// returned buffer for the duration of a test.
+func captureLogs(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	mu.Lock()
+	previousOutput, previousLogger, previousLevel := output, logger, levelVar.Level()
+	output = &buf
+	logger = newLogger(format)
+	mu.Unlock()
+	levelVar.Set(slogLevelTrace)
+	t.Cleanup(func() {
+		mu.Lock()
+		output, logger = previousOutput, previousLogger
+		mu.Unlock()
+		levelVar.Set(previousLevel)
+	})
+	return &buf
+}
+
+func TestHandlersRedact(t *testing.T) {
+	RegisterSecret("handler-secret", `quoted"handler-secret`)
+
+	for _, format := range []string{FormatText, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			buf := captureLogs(t, format)
+			log := With("variable", "DB", "detail", "with handler-secret")
+			log.Trace(`Komodo Request Body: {"type":"CreateVariable","params":{"name":"DB","value":"unregistered-value"}}`)
+			log.Debug("Request headers: map[Authorization:[Bearer header-token] X-Api-Secret:[api-secret-value]]")
+			log.Info("Value is handler-secret")
+			log.With("error", errors.New(`failed with quoted"handler-secret`)).Error("Failed")
+			log.With(slog.Group("request", slog.String("body", "handler-secret"))).Warn("Grouped")
+
+			out := buf.String()
+			if lines := strings.Count(out, "\n"); lines != 5 {
+				t.Fatalf("got %d log lines, want 5:\n%s", lines, out)
+			}
+			for _, secret := range []string{"handler-secret", "unregistered-value", "header-token", "api-secret-value"} {
+				if strings.Contains(out, secret) {
+					t.Errorf("output contains %q:\n%s", secret, out)
+				}
+			}
+			if !strings.Contains(out, "TRACE") || !strings.Contains(out, redactedPlaceholder) {
+				t.Errorf("output lacks TRACE level or placeholder:\n%s", out)
+			}
+		})
+	}
+}
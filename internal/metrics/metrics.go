@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricKind is the Prometheus type of a metric family.
+type metricKind string
+
+const (
+	kindCounter   metricKind = "counter"
+	kindGauge     metricKind = "gauge"
+	kindHistogram metricKind = "histogram"
+)
+
+// family is a metric and all of its labeled series.
+type family struct {
+	name       string
+	help       string
+	kind       metricKind
+	labelNames []string
+	buckets    []float64 // Histograms only
+	series     map[string]*series
+}
+
+// series holds the value of one label combination of a family.
+type series struct {
+	labelValues  []string
+	value        float64  // Counters and gauges
+	bucketCounts []uint64 // Histograms only, cumulative per bucket
+	sum          float64
+	count        uint64
+}
+
+var (
+	mu       sync.Mutex
+	families []*family
+)
+
+var (
+	runDurationBuckets     = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+	requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+var (
+	runsTotal = newFamily("komodo_op_runs_total",
+		"Number of synchronization runs by outcome.", kindCounter, []string{"outcome"}, nil)
+	runDuration = newFamily("komodo_op_run_duration_seconds",
+		"Duration of synchronization runs.", kindHistogram, nil, runDurationBuckets)
+	lastSuccess = newFamily("komodo_op_last_success_timestamp_seconds",
+		"Unix timestamp of the last synchronization run without errors.", kindGauge, nil, nil)
+	consecutiveFailures = newFamily("komodo_op_consecutive_failed_runs",
+		"Number of synchronization runs failed in a row since the last run without errors.", kindGauge, nil, nil)
+	variablesTotal = newFamily("komodo_op_variables_total",
+		"Number of Komodo variables changed by operation, across all runs.", kindCounter, []string{"target", "op"}, nil)
+	lastRunVariables = newFamily("komodo_op_last_run_variables",
+		"Number of Komodo variables changed by operation in the last run.", kindGauge, []string{"target", "op"}, nil)
+	requestsTotal = newFamily("komodo_op_api_requests_total",
+		"Number of API requests by endpoint and status code.", kindCounter, []string{"api", "endpoint", "status"}, nil)
+	requestErrors = newFamily("komodo_op_api_request_errors_total",
+		"Number of failed API requests by endpoint and status code.", kindCounter, []string{"api", "endpoint", "status"}, nil)
+	requestDuration = newFamily("komodo_op_api_request_duration_seconds",
+		"Latency of API requests by endpoint.", kindHistogram, []string{"api", "endpoint"}, requestDurationBuckets)
+)
+
+// newFamily registers a metric family.
+func newFamily(name, help string, kind metricKind, labelNames []string, buckets []float64) *family {
+	f := &family{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+	families = append(families, f)
+	return f
+}
+
+// get returns the series for the given label values, creating it if needed. Callers hold mu.
+func (f *family) get(labelValues ...string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: labelValues}
+		if f.kind == kindHistogram {
+			s.bucketCounts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) add(delta float64, labelValues ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	f.get(labelValues...).value += delta
+}
+
+func (f *family) set(value float64, labelValues ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	f.get(labelValues...).value = value
+}
+
+func (f *family) observe(value float64, labelValues ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	s := f.get(labelValues...)
+	for i, bound := range f.buckets {
+		if value <= bound {
+			s.bucketCounts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// RecordRun records the outcome of a synchronization run.
+func RecordRun(duration time.Duration, success bool, finishedAt time.Time) {
+	outcome := "failure"
+	if success {
+		outcome = "success"
+		lastSuccess.set(float64(finishedAt.Unix()))
+		consecutiveFailures.set(0)
+	} else {
+		consecutiveFailures.add(1)
+	}
+	runsTotal.add(1, outcome)
+	runDuration.observe(duration.Seconds())
+}
+
+// RecordTargetRun records the number of variables changed per operation during a run against a target.
+func RecordTargetRun(target string, counts map[string]int) {
+	for op, count := range counts {
+		variablesTotal.add(float64(count), target, op)
+		lastRunVariables.set(float64(count), target, op)
+	}
+}
+
+// RecordRequest records an API request. A status code of 0 means the request
+// failed before a response was received.
+func RecordRequest(api, endpoint string, statusCode int, duration time.Duration, err error) {
+	status := "error"
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	requestsTotal.add(1, api, endpoint, status)
+	if err != nil || statusCode < 200 || statusCode >= 300 {
+		requestErrors.add(1, api, endpoint, status)
+	}
+	requestDuration.observe(duration.Seconds(), api, endpoint)
+}
+
+// Handler serves all metrics in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		write(w)
+	})
+}
+
+// write renders every family in the Prometheus text exposition format.
+func write(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, f := range families {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		if len(keys) == 0 && len(f.labelNames) == 0 && f.kind != kindHistogram {
+			fmt.Fprintf(w, "%s 0\n", f.name)
+			continue
+		}
+
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != kindHistogram {
+				fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, "", ""), formatValue(s.value))
+				continue
+			}
+			for i, bound := range f.buckets {
+				fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", formatValue(bound)), s.bucketCounts[i])
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", "+Inf"), s.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues, "", ""), formatValue(s.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues, "", ""), s.count)
+		}
+	}
+}
+
+// formatLabels renders a label set, optionally followed by one extra label (used for "le").
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var pairs []string
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraName, escapeLabelValue(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
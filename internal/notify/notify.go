@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/logging"
+	"komodo-op/internal/synchronizer"
+	"komodo-op/internal/util"
+)
+
+// discordMaxLength is the maximum length of a Discord message.
+const discordMaxLength = 2000
+
+// Notifier sends the results of runs to the configured webhooks.
+type Notifier struct {
+	webhooks         []config.Webhook
+	failureThreshold int
+	httpClient       *http.Client
+}
+
+// New creates a notifier for the webhooks of a configuration.
+func New(cfg *config.Config) *Notifier {
+	return &Notifier{
+		webhooks:         cfg.Webhooks,
+		failureThreshold: cfg.WebhookFailureThreshold,
+		httpClient:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify sends the events triggered by a finished run to every webhook subscribed
+// to them. consecutiveFailures is the number of runs failed in a row, including
+// this one, as counted by the caller.
+func (n *Notifier) Notify(report *synchronizer.Report, consecutiveFailures int) {
+	events := n.events(report, consecutiveFailures)
+	if len(events) == 0 {
+		return
+	}
+
+	for _, webhook := range n.webhooks {
+		var matched []string
+		for _, event := range events {
+			for _, subscribed := range webhook.Events {
+				if event == subscribed {
+					matched = append(matched, event)
+				}
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		if err := n.send(webhook, newMessage(report, matched, consecutiveFailures)); err != nil {
+			logging.Error("Failed to send %s webhook notification: %v", webhook.Format, err)
+			continue
+		}
+		logging.Debug("Sent %s webhook notification for events: %s", webhook.Format, strings.Join(matched, ", "))
+	}
+}
+
+// events returns the events triggered by a run after consecutiveFailures failed runs in a row.
+func (n *Notifier) events(report *synchronizer.Report, consecutiveFailures int) []string {
+	var events []string
+	if report.Changes() > 0 {
+		events = append(events, config.EventChanges)
+	}
+	if report.Errors > 0 {
+		events = append(events, config.EventErrors)
+	}
+	if report.DeletionsBlocked() {
+		events = append(events, config.EventDeletionThreshold)
+	}
+	// Notify once per streak of failures rather than after every further failed run
+	if report.Errors > 0 && consecutiveFailures == n.failureThreshold {
+		events = append(events, config.EventConsecutiveFailures)
+	}
+	return events
+}
+
+// message is the redacted summary of a run sent to webhooks.
+type message struct {
+	Title  string               `json:"title"`
+	Text   string               `json:"text"`
+	Events []string             `json:"events"`
+	Failed bool                 `json:"failed"`
+	Report *synchronizer.Report `json:"report"`
+}
+
+// newMessage summarizes a run for the given events. Reports never contain secret
+// values; the text is redacted nonetheless, like log lines.
+func newMessage(report *synchronizer.Report, events []string, consecutiveFailures int) message {
+	title := fmt.Sprintf("komodo-op: %d changes", report.Changes())
+	if report.Errors > 0 {
+		title = fmt.Sprintf("komodo-op: sync failed with %d errors", report.Errors)
+	}
+
+	lines := []string{fmt.Sprintf("Run %s finished in %s: %d changes, %d errors.",
+		report.RunID, report.Duration().Round(time.Millisecond), report.Changes(), report.Errors)}
+	for _, event := range events {
+		if event == config.EventConsecutiveFailures {
+			lines = append(lines, fmt.Sprintf("%d consecutive runs have failed.", consecutiveFailures))
+		}
+	}
+	for _, target := range report.Targets {
+		lines = append(lines, fmt.Sprintf("Target '%s': %d created, %d updated, %d deleted, %d environments, %d accounts, %d errors.",
+			target.Target, target.Created, target.Updated, target.Deleted,
+			len(target.EnvironmentsUpdated), target.AccountsChanged+target.AccountsDeleted, target.Errors))
+		if len(target.ChangedVariables) > 0 {
+			lines = append(lines, "  Changed: "+strings.Join(target.ChangedVariables, ", "))
+		}
+		if len(target.DeletedVariables) > 0 {
+			lines = append(lines, "  Deleted: "+strings.Join(target.DeletedVariables, ", "))
+		}
+		if target.DeletionsBlocked > 0 {
+			lines = append(lines, fmt.Sprintf("  Deletion skipped: %d orphaned variables exceed MAX_DELETIONS.", target.DeletionsBlocked))
+		}
+		if target.DeletionsDeferred > 0 {
+			lines = append(lines, fmt.Sprintf("  Deletion deferred: %d orphaned variables kept during freeze window.", target.DeletionsDeferred))
+		}
+		if target.ExecutionsDeferred > 0 {
+			lines = append(lines, fmt.Sprintf("  Executions deferred: redeploys and post-sync executions of %d changes wait for the end of the freeze window.", target.ExecutionsDeferred))
+		}
+	}
+
+	return message{
+		Title:  logging.Redact(title),
+		Text:   logging.Redact(strings.Join(lines, "\n")),
+		Events: events,
+		Failed: report.Errors > 0,
+		Report: report,
+	}
+}
+
+// send posts a message to a webhook in its format.
+func (n *Notifier) send(webhook config.Webhook, msg message) error {
+	var body []byte
+	var err error
+	headers := map[string]string{"Content-Type": "application/json"}
+
+	switch webhook.Format {
+	case config.WebhookFormatSlack:
+		body, err = json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n%s", msg.Title, msg.Text)})
+	case config.WebhookFormatDiscord:
+		content := fmt.Sprintf("**%s**\n%s", msg.Title, msg.Text)
+		if runes := []rune(content); len(runes) > discordMaxLength {
+			content = string(runes[:discordMaxLength-3]) + "..."
+		}
+		body, err = json.Marshal(map[string]string{"content": content})
+	case config.WebhookFormatNtfy:
+		body = []byte(msg.Text)
+		headers = map[string]string{"Content-Type": "text/plain", "Title": msg.Title, "Tags": "white_check_mark"}
+		if msg.Failed {
+			headers["Tags"] = "warning"
+			headers["Priority"] = "high"
+		}
+	default:
+		body, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := util.ReadAll(resp.Body)
+		return fmt.Errorf("webhook returned status %s: %s", resp.Status, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/synchronizer"
+)
+
+func TestEvents(t *testing.T) {
+	n := &Notifier{failureThreshold: 3}
+	changed := &synchronizer.Report{Targets: []*synchronizer.TargetReport{{Created: 1}}}
+	failed := &synchronizer.Report{Errors: 2}
+	blocked := &synchronizer.Report{Errors: 1, Targets: []*synchronizer.TargetReport{{Errors: 1, DeletionsBlocked: 12}}}
+
+	tests := []struct {
+		name     string
+		report   *synchronizer.Report
+		failures int
+		want     []string
+	}{
+		{"nothing happened", &synchronizer.Report{}, 0, nil},
+		{"changes", changed, 0, []string{config.EventChanges}},
+		{"failure below threshold", failed, 2, []string{config.EventErrors}},
+		{"failure reaching threshold", failed, 3, []string{config.EventErrors, config.EventConsecutiveFailures}},
+		{"failure past threshold", failed, 4, []string{config.EventErrors}},
+		{"deletion threshold", blocked, 1, []string{config.EventErrors, config.EventDeletionThreshold}},
+		{"deletion threshold reaching failure threshold", blocked, 3, []string{config.EventErrors, config.EventDeletionThreshold, config.EventConsecutiveFailures}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.events(tt.report, tt.failures); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageOfBlockedDeletions(t *testing.T) {
+	report := &synchronizer.Report{Errors: 1, Targets: []*synchronizer.TargetReport{{Target: "prod", Errors: 1, DeletionsBlocked: 12}}}
+	msg := newMessage(report, []string{config.EventErrors, config.EventDeletionThreshold}, 1)
+	if !strings.Contains(msg.Text, "Deletion skipped: 12 orphaned variables exceed MAX_DELETIONS.") {
+		t.Errorf("message text lacks the skipped deletions:\n%s", msg.Text)
+	}
+	if !reflect.DeepEqual(msg.Events, []string{config.EventErrors, config.EventDeletionThreshold}) || !msg.Failed {
+		t.Errorf("message = %+v", msg)
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"komodo-op/internal/config"  // Corrected import path
 	"komodo-op/internal/logging" // Corrected import path
-	"komodo-op/internal/util"    // Corrected import path
+	"komodo-op/internal/metrics"
+	"komodo-op/internal/util" // Corrected import path
 )
 
 // Vault represents a 1Password vault.
@@ -20,8 +22,12 @@ type Vault struct {
 
 // Item represents a 1Password item summary.
 type Item struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Vault     Vault     `json:"vault"`
+	Tags      []string  `json:"tags"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 // Field represents a field within a 1Password item.
@@ -35,9 +41,13 @@ type Field struct {
 
 // ItemDetail represents the full details of a 1Password item.
 type ItemDetail struct {
-	ID     string  `json:"id"`
-	Title  string  `json:"title"`
-	Fields []Field `json:"fields"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Vault     Vault     `json:"vault"`
+	Tags      []string  `json:"tags"`
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Fields    []Field   `json:"fields"`
 }
 
 // Client manages communication with the 1Password Connect API.
@@ -55,7 +65,8 @@ func NewClient(httpClient *http.Client, cfg *config.Config) *Client {
 }
 
 // makeRequestGeneric handles making generic requests to the 1Password API.
-func (c *Client) makeRequestGeneric(method, path string, body io.Reader, target interface{}) error {
+// The endpoint is a low-cardinality name of the path used for metrics.
+func (c *Client) makeRequestGeneric(method, path, endpoint string, body io.Reader, target interface{}) error {
 	url := c.cfg.OpConnectHost + path // Path should include /v1 prefix
 	logging.Debug("Making 1Password request: %s %s", method, url)
 	req, err := http.NewRequest(method, url, body)
@@ -69,11 +80,14 @@ func (c *Client) makeRequestGeneric(method, path string, body io.Reader, target
 		req.Header.Set("Content-Type", "application/json") // Only set Content-Type if there's a body
 	}
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		metrics.RecordRequest("1password", method+" "+endpoint, 0, time.Since(start), err)
 		return fmt.Errorf("failed to execute 1Password request to %s: %w", url, err)
 	}
 	defer resp.Body.Close()
+	metrics.RecordRequest("1password", method+" "+endpoint, resp.StatusCode, time.Since(start), nil)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := util.ReadAll(resp.Body)
@@ -95,37 +109,50 @@ func (c *Client) makeRequestGeneric(method, path string, body io.Reader, target
 }
 
 // makeVaultRequest handles requests specific to a vault context.
-func (c *Client) makeVaultRequest(method, itemPath string, target interface{}) error {
-	if c.cfg.OpVaultID == "" {
+func (c *Client) makeVaultRequest(method, vaultID, itemPath, endpoint string, target interface{}) error {
+	if vaultID == "" {
 		return fmt.Errorf("internal error: vault ID not resolved before making vault request")
 	}
 	// Ensure itemPath starts with a slash if not empty, or is just empty
 	if itemPath != "" && !strings.HasPrefix(itemPath, "/") {
 		itemPath = "/" + itemPath
 	}
-	fullPath := fmt.Sprintf("/v1/vaults/%s%s", c.cfg.OpVaultID, itemPath)
-	return c.makeRequestGeneric(method, fullPath, nil, target)
+	fullPath := fmt.Sprintf("/v1/vaults/%s%s", vaultID, itemPath)
+	return c.makeRequestGeneric(method, fullPath, "/v1/vaults/{vault}"+endpoint, nil, target)
 }
 
-// GetItems retrieves a list of item summaries from the configured vault.
+// GetItems retrieves a list of item summaries from the configured vaults, in
+// the order of the vaults.
 func (c *Client) GetItems() ([]Item, error) {
-	var items []Item
-	// Pass "/items" correctly
-	err := c.makeVaultRequest("GET", "/items", &items)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get items from 1Password vault '%s': %w", c.cfg.OpVaultUUID, err)
+	var all []Item
+	for _, vaultID := range c.cfg.OpVaults {
+		var items []Item
+		// Pass "/items" correctly
+		err := c.makeVaultRequest("GET", vaultID, "/items", "/items", &items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get items from 1Password vault '%s': %w", vaultID, err)
+		}
+		logging.Debug("Found %d items in vault '%s'", len(items), vaultID)
+		for i := range items {
+			items[i].Vault.ID = vaultID // Details are fetched from the vault the item was listed in
+		}
+		all = append(all, items...)
 	}
-	logging.Info("Found %d items in vault '%s'", len(items), c.cfg.OpVaultUUID)
-	return items, nil
+	return all, nil
 }
 
-// GetItemDetails retrieves the full details for a specific item ID.
-func (c *Client) GetItemDetails(itemID string) (*ItemDetail, error) {
+// GetItemDetails retrieves the full details for a specific item ID in a vault.
+func (c *Client) GetItemDetails(vaultID, itemID string) (*ItemDetail, error) {
 	var itemDetail ItemDetail
 	itemPath := fmt.Sprintf("/items/%s", itemID) // Path includes leading slash
-	err := c.makeVaultRequest("GET", itemPath, &itemDetail)
+	err := c.makeVaultRequest("GET", vaultID, itemPath, "/items/{item}", &itemDetail)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get details for item %s in vault '%s': %w", itemID, c.cfg.OpVaultUUID, err)
+		return nil, fmt.Errorf("failed to get details for item %s in vault '%s': %w", itemID, vaultID, err)
 	}
 	return &itemDetail, nil
 }
+
+// Ping checks that the 1Password Connect server is reachable.
+func (c *Client) Ping() error {
+	return c.makeRequestGeneric("GET", "/heartbeat", "/heartbeat", nil, nil)
+}
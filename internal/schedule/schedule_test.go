@@ -0,0 +1,119 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// friday is Friday 2024-01-05 10:30 UTC.
+var friday = time.Date(2024, 1, 5, 10, 30, 0, 0, time.UTC)
+
+func date(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"30m", friday.Add(30 * time.Minute)},
+		{"@every 2h", friday.Add(2 * time.Hour)},
+		{"*/15 * * * *", date(1, 5, 10, 45)},
+		{"5/20 * * * *", date(1, 5, 10, 45)},
+		{"1-5/2 * * * *", date(1, 5, 11, 1)},
+		{"0 * * * *", date(1, 5, 11, 0)},
+		{"30 10 * * *", date(1, 6, 10, 30)},
+		{"0 9 * * mon-fri", date(1, 8, 9, 0)},
+		{"0 0 * * 7", date(1, 7, 0, 0)},
+		{"0 12 * Jan,jul *", date(1, 5, 12, 0)},
+		{"0 0 1 * *", date(2, 1, 0, 0)},
+		{"0 0 29 2 *", date(2, 29, 0, 0)},
+		{"0 0 13 * fri", date(1, 12, 0, 0)}, // Either day field matches when both are restricted
+		{"0 0 13 * *", date(1, 13, 0, 0)},
+		{"@hourly", date(1, 5, 11, 0)},
+		{"@daily", date(1, 6, 0, 0)},
+		{"@weekly", date(1, 7, 0, 0)},
+		{"@monthly", date(2, 1, 0, 0)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := s.Next(friday); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", friday, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextIsStrictlyAfter(t *testing.T) {
+	s, err := Parse("30 10 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Seconds into the activation minute do not count as before it
+	if got := s.Next(friday.Add(-time.Second)); !got.Equal(friday) {
+		t.Errorf("Next(10:29:59) = %v, want %v", got, friday)
+	}
+	if got := s.Next(friday.Add(time.Second)); !got.Equal(date(1, 6, 10, 30)) {
+		t.Errorf("Next(10:30:01) = %v, want the next day", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", "expected 5 fields"},
+		{"* * * *", "expected 5 fields"},
+		{"@fortnightly", "expected 5 fields"},
+		{"0s", "interval must be positive"},
+		{"-5m", "interval must be positive"},
+		{"@every soon", "@every needs a positive duration"},
+		{"@every 0s", "@every needs a positive duration"},
+		{"60 * * * *", "minute: value \"60\" out of range 0-59"},
+		{"* 24 * * *", "hour: value \"24\" out of range 0-23"},
+		{"* * 0 * *", "day of month: value \"0\" out of range 1-31"},
+		{"* * * 13 *", "month: value \"13\" out of range 1-12"},
+		{"* * * * 8", "day of week: value \"8\" out of range 0-7"},
+		{"* * * * someday", "day of week: value \"someday\""},
+		{"*/0 * * * *", "invalid step \"0\""},
+		{"5-1 * * * *", "invalid range \"5-1\""},
+		{"0 0 31 2 *", "never matches"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if err == nil {
+				t.Fatalf("Parse(%q) succeeded, want error containing %q", tt.spec, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.spec, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct{ spec, want string }{
+		{"30m", "@every 30m0s"},
+		{"@every 1h", "@every 1h0m0s"},
+		{"@daily", "@daily"},
+		{" 0 9 * * mon-fri ", "0 9 * * mon-fri"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+		}
+		if got := s.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a recurring period of time, such as a weekend change freeze.
+type Window struct {
+	start  *cron
+	length time.Duration
+}
+
+// ParseWindow parses a window of the form "<cron expression> for <duration>",
+// starting at every activation of the cron expression and lasting the duration,
+// e.g. "0 18 * * fri for 62h" from Friday 18:00 until Monday 08:00.
+func ParseWindow(spec string) (Window, error) {
+	startSpec, lengthSpec, ok := strings.Cut(strings.TrimSpace(spec), " for ")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q: expected \"<cron expression> for <duration>\"", spec)
+	}
+	length, err := time.ParseDuration(strings.TrimSpace(lengthSpec))
+	if err != nil || length <= 0 {
+		return Window{}, fmt.Errorf("invalid window %q: duration must be positive (e.g., \"2h\")", spec)
+	}
+	start, err := parseCron(strings.TrimSpace(startSpec))
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %w", spec, err)
+	}
+	return Window{start: start, length: length}, nil
+}
+
+// ParseWindows parses a semicolon-separated list of windows.
+func ParseWindows(value string) ([]Window, error) {
+	var windows []Window
+	for _, spec := range strings.Split(value, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		window, err := ParseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// String returns the specification the window was parsed from.
+func (w Window) String() string {
+	return fmt.Sprintf("%s for %s", w.start, w.length)
+}
+
+// Active reports whether t lies within the window, and when the window ends.
+// Of overlapping occurrences, the one ending last is returned.
+func (w Window) Active(t time.Time) (time.Time, bool) {
+	var end time.Time
+	for start := w.start.Next(t.Add(-w.length)); !start.IsZero() && !start.After(t); start = w.start.Next(start) {
+		if occurrenceEnd := start.Add(w.length); occurrenceEnd.After(t) && occurrenceEnd.After(end) {
+			end = occurrenceEnd
+		}
+	}
+	return end, !end.IsZero()
+}
+
+// ActiveAny reports whether t lies within any of the windows, and when the
+// last of them ends.
+func ActiveAny(windows []Window, t time.Time) (time.Time, bool) {
+	var end time.Time
+	for _, window := range windows {
+		if windowEnd, ok := window.Active(t); ok && windowEnd.After(end) {
+			end = windowEnd
+		}
+	}
+	return end, !end.IsZero()
+}
+
+// LongestGap returns the longest time between consecutive activations of a
+// schedule within span after from, e.g. to tell how stale its last run may be.
+func LongestGap(s Schedule, from time.Time, span time.Duration) time.Duration {
+	var longest time.Duration
+	// Bounded for schedules activating every minute
+	for t, i := from, 0; t.Before(from.Add(span)) && i < 100000; i++ {
+		next := s.Next(t)
+		if next.IsZero() {
+			break
+		}
+		if gap := next.Sub(t); gap > longest {
+			longest = gap
+		}
+		t = next
+	}
+	return longest
+}
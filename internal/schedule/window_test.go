@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustParseWindow(t *testing.T, spec string) Window {
+	t.Helper()
+	w, err := ParseWindow(spec)
+	if err != nil {
+		t.Fatalf("ParseWindow(%q) error = %v", spec, err)
+	}
+	return w
+}
+
+func TestWindowActive(t *testing.T) {
+	weekend := mustParseWindow(t, "0 18 * * fri for 62h")
+	monday := date(1, 8, 8, 0)
+	tests := []struct {
+		name    string
+		window  Window
+		at      time.Time
+		want    time.Time
+		wantNow bool
+	}{
+		{"before the start", weekend, date(1, 5, 17, 59), time.Time{}, false},
+		{"at the start", weekend, date(1, 5, 18, 0), monday, true},
+		{"within", weekend, date(1, 7, 12, 0), monday, true},
+		{"just before the end", weekend, monday.Add(-time.Second), monday, true},
+		{"at the end", weekend, monday, time.Time{}, false},
+		{"overlapping occurrences", mustParseWindow(t, "0 * * * * for 150m"), friday, date(1, 5, 12, 30), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, active := tt.window.Active(tt.at)
+			if active != tt.wantNow || !end.Equal(tt.want) {
+				t.Errorf("Active(%v) = %v, %v, want %v, %v", tt.at, end, active, tt.want, tt.wantNow)
+			}
+		})
+	}
+}
+
+func TestActiveAny(t *testing.T) {
+	windows, err := ParseWindows("0 10 * * * for 1h; 0 9 * * fri for 4h;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("ParseWindows() returned %d windows, want 2", len(windows))
+	}
+	if end, active := ActiveAny(windows, friday); !active || !end.Equal(date(1, 5, 13, 0)) {
+		t.Errorf("ActiveAny(Friday) = %v, %v, want the later end", end, active)
+	}
+	if end, active := ActiveAny(windows, friday.AddDate(0, 0, 1)); !active || !end.Equal(date(1, 6, 11, 0)) {
+		t.Errorf("ActiveAny(Saturday) = %v, %v, want the daily window", end, active)
+	}
+	if _, active := ActiveAny(windows, date(1, 6, 14, 0)); active {
+		t.Error("ActiveAny() outside every window reported active")
+	}
+	if _, active := ActiveAny(nil, friday); active {
+		t.Error("ActiveAny() without windows reported active")
+	}
+}
+
+func TestParseWindows(t *testing.T) {
+	if windows, err := ParseWindows(" ; "); err != nil || windows != nil {
+		t.Errorf("ParseWindows(blank) = %v, %v, want none", windows, err)
+	}
+	if got := mustParseWindow(t, " 0 18 * * fri for 62h ").String(); got != "0 18 * * fri for 62h0m0s" {
+		t.Errorf("String() = %q", got)
+	}
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"0 18 * * fri", "expected \"<cron expression> for <duration>\""},
+		{"0 18 * * fri for soon", "duration must be positive"},
+		{"0 18 * * fri for -1h", "duration must be positive"},
+		{"0 18 * * funday for 2h", "day of week"},
+		{"30m for 2h", "expected 5 fields"},
+	}
+	for _, tt := range tests {
+		_, err := ParseWindows("0 0 * * * for 1h;" + tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseWindows(%q) error = %v, want it to contain %q", tt.spec, err, tt.want)
+		}
+	}
+}
+
+func TestLongestGap(t *testing.T) {
+	weekdays, err := Parse("0 9 * * mon-fri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		schedule Schedule
+		want     time.Duration
+	}{
+		{"interval", Every(5 * time.Minute), 5 * time.Minute},
+		{"over a weekend", weekdays, 72 * time.Hour}, // Friday 09:00 until Monday 09:00
+	}
+	for _, tt := range tests {
+		if got := LongestGap(tt.schedule, friday, 7*24*time.Hour); got != tt.want {
+			t.Errorf("%s: LongestGap() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
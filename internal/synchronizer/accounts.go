@@ -0,0 +1,273 @@
+package synchronizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"komodo-op/internal/audit"
+	"komodo-op/internal/komodoclient"
+	"komodo-op/internal/opclient"
+)
+
+// Item tags routing an item to a Komodo account instead of global variables.
+var accountTags = map[string]komodoclient.AccountType{
+	"komodo-git":      komodoclient.AccountGitProvider,
+	"komodo-registry": komodoclient.AccountDockerRegistry,
+}
+
+// Account types that are reconciled, in processing order.
+var accountTypes = []komodoclient.AccountType{
+	komodoclient.AccountGitProvider,
+	komodoclient.AccountDockerRegistry,
+}
+
+// Name of the variable recording which accounts are managed by this tool, and
+// the revision of the item each was last written from. Accounts have no
+// description, so the managed marker cannot be put on them directly.
+const accountsStateVariable = "KOMODO_OP__MANAGED_ACCOUNTS"
+
+// desiredAccount is an account built from a 1Password item.
+type desiredAccount struct {
+	kind     komodoclient.AccountType
+	params   komodoclient.AccountParams
+	item     string // Item title, for logging
+	revision string // Item ID and version, telling token changes apart when Komodo hides tokens
+	source   variableSource
+}
+
+// accountResult counts the outcome of the account phase.
+type accountResult struct {
+	created int
+	updated int
+	deleted int
+	errors  int
+}
+
+// accountKey uniquely identifies an account of a given type.
+func accountKey(kind komodoclient.AccountType, domain, username string) string {
+	return fmt.Sprintf("%s:%s@%s", kind, username, domain)
+}
+
+func (a desiredAccount) key() string {
+	return accountKey(a.kind, a.params.Domain, a.params.Username)
+}
+
+// accountTypeForTags returns the account type an item is routed to based on its tags, if any.
+func accountTypeForTags(tags []string) (komodoclient.AccountType, bool) {
+	for _, tag := range tags {
+		if kind, ok := accountTags[tag]; ok {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// accountFromItem builds an account from the fields of an item. The domain is read
+// from a field labeled "domain", the username from a "username" field (or USERNAME
+// purpose) and the token from a "token" field (or PASSWORD purpose). Git provider
+// accounts use HTTPS unless an "https" field is set to "false".
+func accountFromItem(kind komodoclient.AccountType, item *opclient.ItemDetail) (desiredAccount, error) {
+	account := desiredAccount{kind: kind, item: item.Title, revision: fmt.Sprintf("%s@%d", item.ID, item.Version)}
+	account.source = variableSource{itemID: item.ID, itemVersion: item.Version}
+	https := true
+	for _, field := range item.Fields {
+		switch label := strings.ToLower(field.Label); {
+		case label == "domain":
+			account.params.Domain = strings.TrimSpace(field.Value)
+		case label == "username" || field.Purpose == "USERNAME":
+			account.params.Username = strings.TrimSpace(field.Value)
+		case label == "token" || (field.Purpose == "PASSWORD" && account.params.Token == ""):
+			account.params.Token = field.Value
+		case label == "https":
+			https = !strings.EqualFold(strings.TrimSpace(field.Value), "false")
+		}
+	}
+	if kind == komodoclient.AccountGitProvider {
+		account.params.HTTPS = &https
+	}
+
+	var missing []string
+	if account.params.Domain == "" {
+		missing = append(missing, "domain")
+	}
+	if account.params.Username == "" {
+		missing = append(missing, "username")
+	}
+	if account.params.Token == "" {
+		missing = append(missing, "token")
+	}
+	if len(missing) > 0 {
+		return account, fmt.Errorf("item '%s' is missing account fields: %s", item.Title, strings.Join(missing, ", "))
+	}
+	return account, nil
+}
+
+// loadManagedAccounts reads the accounts managed by this tool, with the item
+// revision each was last written from. Records of earlier versions list the
+// keys only, their revisions are empty.
+func (t *targetSync) loadManagedAccounts() (map[string]string, bool, error) {
+	variable, found, err := t.komodoClient.GetVariable(accountsStateVariable)
+	if err != nil {
+		return nil, false, err
+	}
+	managed := make(map[string]string)
+	if !found || variable.Value == "" {
+		return managed, found, nil
+	}
+	if err := json.Unmarshal([]byte(variable.Value), &managed); err == nil {
+		return managed, found, nil
+	}
+	var keys []string
+	if err := json.Unmarshal([]byte(variable.Value), &keys); err != nil {
+		return nil, found, fmt.Errorf("failed to decode variable '%s': %w", accountsStateVariable, err)
+	}
+	for _, key := range keys {
+		managed[key] = ""
+	}
+	return managed, found, nil
+}
+
+// saveManagedAccounts records the accounts managed by this tool.
+func (t *targetSync) saveManagedAccounts(managed map[string]string, exists bool) error {
+	value, err := json.Marshal(managed) // Keys are sorted
+	if err != nil {
+		return err
+	}
+	if exists {
+		return t.komodoClient.UpdateVariableValue(accountsStateVariable, string(value))
+	}
+	description := fmt.Sprintf("%s Accounts managed by komodo-op, do not edit", managedByMarker)
+	return t.komodoClient.CreateVariable(accountsStateVariable, string(value), description, false)
+}
+
+// syncAccounts creates and updates the desired accounts and deletes accounts
+// previously created by this tool that are no longer backed by an item, unless
+// keepRemoved is set for a partial run or a freeze window.
+func (t *targetSync) syncAccounts(desired []desiredAccount, keepRemoved bool) accountResult {
+	var result accountResult
+
+	managed, stateExists, err := t.loadManagedAccounts()
+	if err != nil {
+		t.log.Error("Failed to read managed accounts, skipping account phase: %v", err)
+		result.errors++
+		return result
+	}
+	if len(desired) == 0 && len(managed) == 0 {
+		t.log.Debug("No accounts to manage.")
+		return result
+	}
+
+	existing := make(map[komodoclient.AccountType]map[string]komodoclient.Account)
+	for _, kind := range accountTypes {
+		accounts, err := t.komodoClient.ListAccounts(kind)
+		if err != nil {
+			t.log.Error("Failed to list Komodo %ss, skipping them: %v", kind, err)
+			result.errors++
+			continue
+		}
+		existing[kind] = make(map[string]komodoclient.Account, len(accounts))
+		for _, account := range accounts {
+			existing[kind][accountKey(kind, account.Domain, account.Username)] = account
+		}
+	}
+
+	nextManaged := make(map[string]string)
+	desiredKeys := make(map[string]bool)
+	for _, account := range desired {
+		key := account.key()
+		desiredKeys[key] = true
+		current, listed := existing[account.kind]
+		if !listed {
+			if revision, ok := managed[key]; ok {
+				nextManaged[key] = revision
+			}
+			continue
+		}
+
+		if found, ok := current[key]; ok {
+			// Komodo may not return tokens, the revision of the item tells whether it changed then
+			tokenChanged := found.Token != account.params.Token
+			if found.Token == "" {
+				tokenChanged = managed[key] != account.revision
+			}
+			if !tokenChanged && (account.params.HTTPS == nil || found.HTTPS == *account.params.HTTPS) {
+				t.log.Debug("  Account '%s' is up to date.", key)
+				nextManaged[key] = account.revision
+				continue
+			}
+			t.log.Info("  Account '%s' from item '%s' changed, attempting update.", key, account.item)
+			if err := t.komodoClient.UpdateAccount(account.kind, found.ID.OID, account.params); err != nil {
+				t.log.Error("    Failed to update account '%s': %v", key, err)
+				nextManaged[key] = managed[key]
+				result.errors++
+				continue
+			}
+			nextManaged[key] = account.revision
+			result.updated++
+			t.auditAccount(audit.OpUpdate, key, account)
+			continue
+		}
+
+		t.log.Info("  Account '%s' from item '%s' does not exist, attempting create.", key, account.item)
+		if err := t.komodoClient.CreateAccount(account.kind, account.params); err != nil {
+			t.log.Error("    Failed to create account '%s': %v", key, err)
+			result.errors++
+			continue
+		}
+		nextManaged[key] = account.revision
+		result.created++
+		t.auditAccount(audit.OpCreate, key, account)
+	}
+
+	for key, revision := range managed {
+		if desiredKeys[key] {
+			continue
+		}
+		if keepRemoved {
+			nextManaged[key] = revision
+			continue
+		}
+		kind := komodoclient.AccountType(strings.SplitN(key, ":", 2)[0])
+		current, listed := existing[kind]
+		if !listed {
+			nextManaged[key] = revision
+			continue
+		}
+		found, ok := current[key]
+		if !ok {
+			t.log.Debug("  Orphaned account '%s' is already gone.", key)
+			continue
+		}
+		t.log.Info("  Found orphaned account '%s', attempting delete.", key)
+		if err := t.komodoClient.DeleteAccount(kind, found.ID.OID); err != nil {
+			t.log.Error("    Failed to delete account '%s': %v", key, err)
+			nextManaged[key] = revision
+			result.errors++
+			continue
+		}
+		result.deleted++
+		t.writeAudit(audit.Record{Op: audit.OpDelete, Account: key})
+	}
+
+	if !sameRevisions(managed, nextManaged) || !stateExists {
+		if err := t.saveManagedAccounts(nextManaged, stateExists); err != nil {
+			t.log.Error("Failed to record managed accounts: %v", err)
+			result.errors++
+		}
+	}
+	return result
+}
+
+// sameRevisions reports whether two records hold the same accounts and revisions.
+func sameRevisions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, revision := range a {
+		if other, ok := b[key]; !ok || other != revision {
+			return false
+		}
+	}
+	return true
+}
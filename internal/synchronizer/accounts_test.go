@@ -0,0 +1,73 @@
+package synchronizer
+
+import (
+	"reflect"
+	"testing"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/komodoclient"
+	"komodo-op/internal/opclient"
+)
+
+func gitAccountItem(version int, token string) *opclient.ItemDetail {
+	item := &opclient.ItemDetail{ID: "item1", Title: "GitHub", Version: version, Tags: []string{"komodo-git"}}
+	item.Fields = []opclient.Field{
+		{Label: "domain", Value: "github.com"},
+		{Label: "username", Value: "bot"},
+		{Label: "token", Value: token},
+	}
+	return item
+}
+
+func TestSyncAccountsWithHiddenTokens(t *testing.T) {
+	for _, hidden := range []bool{false, true} {
+		komodo, client := newFakeKomodo(t)
+		komodo.hideTokens = hidden
+		target := newTestTarget(&config.Config{}, client)
+		sync := func(item *opclient.ItemDetail) accountResult {
+			account, err := accountFromItem(komodoclient.AccountGitProvider, item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return target.syncAccounts([]desiredAccount{account}, false)
+		}
+
+		if result := sync(gitAccountItem(1, "t1")); result.created != 1 || result.errors != 0 {
+			t.Fatalf("hidden=%t: first sync = %+v, want one account created", hidden, result)
+		}
+		komodo.takeWrites()
+
+		// Nothing is written while the item does not change
+		if result := sync(gitAccountItem(1, "t1")); result != (accountResult{}) {
+			t.Errorf("hidden=%t: unchanged sync = %+v, want nothing done", hidden, result)
+		}
+		if writes := komodo.takeWrites(); writes != nil {
+			t.Errorf("hidden=%t: unchanged sync wrote %v", hidden, writes)
+		}
+
+		// A new token is written whether Komodo returns tokens or not
+		if result := sync(gitAccountItem(2, "t2")); result.updated != 1 {
+			t.Errorf("hidden=%t: sync of a new token = %+v, want one account updated", hidden, result)
+		}
+		if token := komodo.accounts[komodoclient.AccountGitProvider][0].Token; token != "t2" {
+			t.Errorf("hidden=%t: token = %q, want t2", hidden, token)
+		}
+		if result := sync(gitAccountItem(2, "t2")); result != (accountResult{}) {
+			t.Errorf("hidden=%t: sync after the update = %+v, want nothing done", hidden, result)
+		}
+	}
+}
+
+func TestLoadManagedAccountsOfEarlierVersions(t *testing.T) {
+	komodo, client := newFakeKomodo(t)
+	komodo.variables[accountsStateVariable] = komodoclient.VariableResponse{Name: accountsStateVariable, Value: `["GitProviderAccount:bot@github.com"]`}
+	target := newTestTarget(&config.Config{}, client)
+
+	managed, found, err := target.loadManagedAccounts()
+	if err != nil || !found {
+		t.Fatalf("loadManagedAccounts() = %v, %v", found, err)
+	}
+	if want := map[string]string{"GitProviderAccount:bot@github.com": ""}; !reflect.DeepEqual(managed, want) {
+		t.Errorf("managed = %v, want %v", managed, want)
+	}
+}
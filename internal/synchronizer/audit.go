@@ -0,0 +1,88 @@
+package synchronizer
+
+import (
+	"fmt"
+	"time"
+
+	"komodo-op/internal/audit"
+	"komodo-op/internal/opclient"
+)
+
+// auditOps maps the actions of syncKomodoSecret to audit log operations.
+var auditOps = map[syncAction]string{
+	actionCreated:   audit.OpCreate,
+	actionUpdated:   audit.OpUpdate,
+	actionCorrected: audit.OpCorrect,
+}
+
+// itemVaultID returns the vault an item was read from.
+func (s *Synchronizer) itemVaultID(item *opclient.ItemDetail) string {
+	if item.Vault.ID != "" {
+		return item.Vault.ID
+	}
+	return s.cfg.OpVaultID
+}
+
+// auditVariable records a variable written by syncKomodoSecret in the audit log.
+func (t *targetSync) auditVariable(action syncAction, variable desiredVariable) {
+	if !audit.Enabled() {
+		return
+	}
+	t.writeAudit(audit.Record{
+		Op:          auditOps[action],
+		Variable:    variable.name,
+		VaultID:     variable.source.vaultID,
+		ItemID:      variable.source.itemID,
+		FieldID:     variable.source.fieldID,
+		ItemVersion: variable.source.itemVersion,
+		Fingerprint: audit.Fingerprint(variable.value),
+	})
+}
+
+// auditDelete records an orphaned variable deleted from Komodo in the audit log.
+func (t *targetSync) auditDelete(name string) {
+	t.writeAudit(audit.Record{Op: audit.OpDelete, Variable: name})
+}
+
+// auditAccount records an account created or updated by syncAccounts in the
+// audit log, with a fingerprint of its token.
+func (t *targetSync) auditAccount(op, key string, account desiredAccount) {
+	if !audit.Enabled() {
+		return
+	}
+	t.writeAudit(audit.Record{
+		Op:          op,
+		Account:     key,
+		VaultID:     account.source.vaultID,
+		ItemID:      account.source.itemID,
+		ItemVersion: account.source.itemVersion,
+		Fingerprint: audit.Fingerprint(account.params.Token),
+	})
+}
+
+// auditEnvironment records the managed keys of a resource environment rewritten
+// by syncEnvironments in the audit log, with fingerprints of the values written.
+func (t *targetSync) auditEnvironment(target envTarget, changes envChanges, values map[string]string) {
+	if !audit.Enabled() {
+		return
+	}
+	resource := fmt.Sprintf("%s/%s", target.kind, target.name)
+	for _, key := range changes.created {
+		t.writeAudit(audit.Record{Op: audit.OpCreate, Resource: resource, Variable: key, Fingerprint: audit.Fingerprint(values[key])})
+	}
+	for _, key := range changes.updated {
+		t.writeAudit(audit.Record{Op: audit.OpUpdate, Resource: resource, Variable: key, Fingerprint: audit.Fingerprint(values[key])})
+	}
+	for _, key := range changes.deleted {
+		t.writeAudit(audit.Record{Op: audit.OpDelete, Resource: resource, Variable: key})
+	}
+}
+
+func (t *targetSync) writeAudit(record audit.Record) {
+	record.Timestamp = time.Now().UTC()
+	record.RunID = t.runID
+	record.Target = t.target.Name
+	if err := audit.Write(record); err != nil {
+		t.log.With("variable", record.Variable, "resource", record.Resource, "account", record.Account).Error("Failed to write audit record: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package synchronizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"komodo-op/internal/audit"
+	"komodo-op/internal/config"
+	"komodo-op/internal/komodoclient"
+)
+
+// readAudit enables the audit log for the duration of a test and returns a
+// function reading the records written so far.
+func readAudit(t *testing.T) func() []audit.Record {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := audit.Configure(path, 0, 0, "test-key", ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { audit.Configure("", 0, 0, "", "") })
+	return func() []audit.Record {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "s3cr3t") || strings.Contains(string(data), "token-1") {
+			t.Errorf("audit log contains a value:\n%s", data)
+		}
+		var records []audit.Record
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			var record audit.Record
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatal(err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestAuditEnvironments(t *testing.T) {
+	records := readAudit(t)
+	komodo, client := newFakeKomodo(t)
+	komodo.resources[komodoclient.ResourceStack] = []komodoclient.Resource{{
+		Name:   "app",
+		Config: komodoclient.ResourceConfig{Environment: envBlockBegin + "\nOLD=1\nKEPT=2\n" + envBlockEnd},
+	}}
+	target := newTestTarget(&config.Config{}, client)
+
+	desired := map[envTarget]map[string]string{
+		{kind: komodoclient.ResourceStack, name: "app"}: {"DB_PASSWORD": "s3cr3t", "KEPT": "3"},
+	}
+	if updates, errs := target.syncEnvironments(desired, false); len(updates) != 1 || errs != 0 {
+		t.Fatalf("syncEnvironments() = %v, %d", updates, errs)
+	}
+
+	got := records()
+	want := []struct{ op, key string }{
+		{audit.OpCreate, "DB_PASSWORD"},
+		{audit.OpUpdate, "KEPT"},
+		{audit.OpDelete, "OLD"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		record := got[i]
+		if record.Op != w.op || record.Variable != w.key || record.Resource != "Stack/app" || record.Target != "test" {
+			t.Errorf("record %d = %+v, want %s of %s in Stack/app", i, record, w.op, w.key)
+		}
+		if hasFingerprint := record.Fingerprint != ""; hasFingerprint != (w.op != audit.OpDelete) {
+			t.Errorf("record %d has fingerprint %q", i, record.Fingerprint)
+		}
+	}
+	if got[0].Fingerprint != audit.Fingerprint("s3cr3t") {
+		t.Errorf("fingerprint = %q, want the one of the value written", got[0].Fingerprint)
+	}
+}
+
+func TestAuditAccounts(t *testing.T) {
+	records := readAudit(t)
+	_, client := newFakeKomodo(t)
+	target := newTestTarget(&config.Config{}, client)
+	account, err := accountFromItem(komodoclient.AccountGitProvider, gitAccountItem(1, "token-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target.syncAccounts([]desiredAccount{account}, false)
+	target.syncAccounts(nil, false)
+
+	got := records()
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want 2: %+v", len(got), got)
+	}
+	key := account.key()
+	if got[0].Op != audit.OpCreate || got[0].Account != key || got[0].ItemID != "item1" || got[0].ItemVersion != 1 || got[0].Fingerprint != audit.Fingerprint("token-1") {
+		t.Errorf("create record = %+v", got[0])
+	}
+	if got[1].Op != audit.OpDelete || got[1].Account != key || got[1].Fingerprint != "" {
+		t.Errorf("delete record = %+v", got[1])
+	}
+}
@@ -0,0 +1,132 @@
+package synchronizer
+
+import (
+	"strings"
+
+	"komodo-op/internal/logging"
+	"komodo-op/internal/opclient"
+)
+
+// desiredVariable is a Komodo variable built from a 1Password field.
+type desiredVariable struct {
+	name     string
+	value    string
+	isSecret bool
+	source   variableSource
+}
+
+// variableSource identifies the 1Password field a variable is built from.
+type variableSource struct {
+	vaultID     string
+	itemID      string
+	fieldID     string
+	itemVersion int
+}
+
+// desiredState is what a target should contain according to 1Password.
+type desiredState struct {
+	variables     []desiredVariable
+	expectedNames map[string]bool // Managed variable names that must not be deleted
+	environments  map[envTarget]map[string]string
+	accounts      []desiredAccount
+	skipped       int // Items/fields skipped
+	excluded      int // Items excluded by the target's filters
+	accountErrors int // Items tagged as accounts that could not be parsed
+}
+
+// buildDesiredState routes the fields of every included item to variables,
+// resource environments or accounts.
+func (t *targetSync) buildDesiredState(items []*opclient.ItemDetail) *desiredState {
+	state := &desiredState{
+		expectedNames: make(map[string]bool),
+		environments:  make(map[envTarget]map[string]string),
+	}
+	// Never treat the account ownership record as an orphaned variable
+	state.expectedNames[accountsStateVariable] = true
+	variableIndex := make(map[string]int) // Index of each variable in state.variables
+
+	t.log.Info("Processing %d items from 1Password...", len(items))
+	for _, item := range items {
+		log := t.log.With("item_id", item.ID)
+		log.Debug("Processing 1P item: '%s' (ID: %s)", item.Title, item.ID)
+		if !t.includes(item) {
+			log.Debug("  Item '%s' is excluded from target '%s'.", item.Title, t.target.Name)
+			state.excluded++
+			continue
+		}
+
+		if len(item.Fields) == 0 {
+			log.Info("  Item '%s' has no fields. Skipping.", item.Title)
+			state.skipped++
+			continue
+		}
+
+		if kind, isAccount := accountTypeForTags(item.Tags); isAccount {
+			account, err := accountFromItem(kind, item)
+			if err != nil {
+				log.Error("  Cannot build %s: %v", kind, err)
+				state.accountErrors++
+				continue
+			}
+			logging.RegisterSecret(account.params.Token)
+			account.source.vaultID = t.itemVaultID(item)
+			log.Debug("  Item '%s' is routed to %s '%s'", item.Title, kind, account.key())
+			state.accounts = append(state.accounts, account)
+			continue
+		}
+
+		target, toEnv := envTargetForTags(item.Tags)
+		if toEnv {
+			log.Debug("  Item '%s' is routed to the environment of %s", item.Title, target)
+			if state.environments[target] == nil {
+				state.environments[target] = make(map[string]string)
+			}
+		}
+
+		for _, field := range item.Fields {
+			if field.Label == "" || field.Value == "" {
+				log.Debug("  Skipping field ID %s in item '%s' (label or value is empty)", field.ID, item.Title)
+				state.skipped++
+				continue
+			}
+
+			if toEnv {
+				if strings.ContainsAny(field.Value, "\r\n") {
+					log.Warn("  Field '%s' in item '%s' is multi-line and cannot be written to an environment. Skipping.", field.Label, item.Title)
+					state.skipped++
+					continue
+				}
+				envKey := formatEnvKey(field.Label)
+				logging.RegisterSecret(field.Value)
+				state.environments[target][envKey] = field.Value
+				log.Debug("  Added expected %s environment key: %s", target, envKey)
+				continue
+			}
+
+			komodoName := formatKomodoName(item.Title, field.Label)
+			state.expectedNames[komodoName] = true
+			isSecret := t.isSecretVariable(komodoName, field)
+			if isSecret {
+				logging.RegisterSecret(field.Value)
+			}
+			variable := desiredVariable{
+				name:     komodoName,
+				value:    field.Value,
+				isSecret: isSecret,
+				source:   variableSource{vaultID: t.itemVaultID(item), itemID: item.ID, fieldID: field.ID, itemVersion: item.Version},
+			}
+			// Items of later vaults win on name clashes
+			if i, exists := variableIndex[komodoName]; exists {
+				log.Warn("  Variable '%s' of item '%s' overrides the one of item %s.", komodoName, item.Title, state.variables[i].source.itemID)
+				state.variables[i] = variable
+				continue
+			}
+			variableIndex[komodoName] = len(state.variables)
+			state.variables = append(state.variables, variable)
+			log.Debug("  Added expected Komodo name: %s", komodoName)
+		}
+	}
+	t.log.Info("Finished processing 1Password items. Found %d secrets to potentially sync. Skipped %d items/fields, excluded %d items.", len(state.variables), state.skipped, state.excluded)
+
+	return state
+}
@@ -0,0 +1,246 @@
+package synchronizer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"komodo-op/internal/komodoclient"
+)
+
+// Lines delimiting the part of a resource environment managed by this tool.
+// Everything outside of this block is left untouched.
+const (
+	envBlockBegin = "# BEGIN komodo-op managed block (" + managedByMarker + " do not edit)"
+	envBlockEnd   = "# END komodo-op managed block"
+)
+
+// Item tag prefixes routing an item's fields into a resource environment instead of global variables.
+var envTagPrefixes = map[string]komodoclient.ResourceType{
+	"komodo-stack:":      komodoclient.ResourceStack,
+	"komodo-deployment:": komodoclient.ResourceDeployment,
+	"komodo-repo:":       komodoclient.ResourceRepo,
+}
+
+// Resource types whose environments are reconciled, in processing order.
+var envResourceTypes = []komodoclient.ResourceType{
+	komodoclient.ResourceStack,
+	komodoclient.ResourceDeployment,
+	komodoclient.ResourceRepo,
+}
+
+// envTarget identifies a Komodo resource whose environment receives secrets.
+type envTarget struct {
+	kind komodoclient.ResourceType
+	name string
+}
+
+func (t envTarget) String() string {
+	return fmt.Sprintf("%s '%s'", strings.ToLower(string(t.kind)), t.name)
+}
+
+// envChanges lists the managed keys changed by a merge.
+type envChanges struct {
+	created []string
+	updated []string
+	deleted []string
+}
+
+func (c envChanges) empty() bool {
+	return len(c.created) == 0 && len(c.updated) == 0 && len(c.deleted) == 0
+}
+
+func (c envChanges) String() string {
+	return fmt.Sprintf("created: %d, updated: %d, deleted: %d", len(c.created), len(c.updated), len(c.deleted))
+}
+
+// envUpdate records a resource environment that was rewritten during a run.
+type envUpdate struct {
+	target  envTarget
+	changes envChanges
+}
+
+// envTargetForTags returns the resource an item is routed to based on its tags, if any.
+func envTargetForTags(tags []string) (envTarget, bool) {
+	for _, tag := range tags {
+		for prefix, kind := range envTagPrefixes {
+			if strings.HasPrefix(tag, prefix) {
+				name := strings.TrimSpace(strings.TrimPrefix(tag, prefix))
+				if name != "" {
+					return envTarget{kind: kind, name: name}, true
+				}
+			}
+		}
+	}
+	return envTarget{}, false
+}
+
+// formatEnvKey formats a field label into an environment variable name.
+func formatEnvKey(fieldLabel string) string {
+	return formatKomodoName(fieldLabel, "")
+}
+
+// parseManagedEnv splits an environment into the lines before the managed block,
+// the keys of the block and the lines after it.
+func parseManagedEnv(environment string) (before []string, current map[string]string, after []string) {
+	current = make(map[string]string)
+
+	trimmed := strings.TrimRight(environment, "\n")
+	var lines []string
+	if trimmed != "" {
+		lines = strings.Split(trimmed, "\n")
+	}
+
+	const (
+		stateBefore = iota
+		stateInside
+		stateAfter
+	)
+	state := stateBefore
+	for _, line := range lines {
+		switch state {
+		case stateBefore:
+			if strings.TrimSpace(line) == envBlockBegin {
+				state = stateInside
+				continue
+			}
+			before = append(before, line)
+		case stateInside:
+			if strings.TrimSpace(line) == envBlockEnd {
+				state = stateAfter
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if ok && strings.TrimSpace(key) != "" {
+				current[strings.TrimSpace(key)] = value
+			}
+		case stateAfter:
+			after = append(after, line)
+		}
+	}
+	return before, current, after
+}
+
+// mergeManagedEnv rewrites the managed block of an environment so it holds exactly
+// the desired keys. Lines outside of the block are preserved as-is. The original
+// environment is returned unchanged if no managed key differs.
+func mergeManagedEnv(environment string, desired map[string]string) (string, envChanges) {
+	before, current, after := parseManagedEnv(environment)
+
+	var changes envChanges
+	for key, value := range desired {
+		oldValue, exists := current[key]
+		if !exists {
+			changes.created = append(changes.created, key)
+		} else if oldValue != value {
+			changes.updated = append(changes.updated, key)
+		}
+	}
+	for key := range current {
+		if _, exists := desired[key]; !exists {
+			changes.deleted = append(changes.deleted, key)
+		}
+	}
+	sort.Strings(changes.created)
+	sort.Strings(changes.updated)
+	sort.Strings(changes.deleted)
+
+	if changes.empty() {
+		return environment, changes
+	}
+
+	result := append([]string{}, before...)
+	if len(desired) > 0 {
+		keys := make([]string, 0, len(desired))
+		for key := range desired {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		result = append(result, envBlockBegin)
+		for _, key := range keys {
+			result = append(result, fmt.Sprintf("%s=%s", key, desired[key]))
+		}
+		result = append(result, envBlockEnd)
+	}
+	result = append(result, after...)
+
+	return strings.Join(result, "\n"), changes
+}
+
+// syncEnvironments reconciles the managed block of every resource environment
+// against the desired keys. Resources holding a managed block but no longer
+// targeted by any item have their block removed. With keepRemoved, as in partial
+// runs where the desired keys of the items not covered are unknown and during
+// freeze windows, keys are only added and updated.
+// Returns the resources updated and the number of errors encountered.
+func (t *targetSync) syncEnvironments(desired map[envTarget]map[string]string, keepRemoved bool) ([]envUpdate, int) {
+	var updates []envUpdate
+	errorCount := 0
+	seen := make(map[envTarget]bool)
+
+	for _, kind := range envResourceTypes {
+		resources, err := t.komodoClient.ListResources(kind)
+		if err != nil {
+			t.log.Error("Failed to list Komodo %s, skipping their environments: %v", kind.Plural(), err)
+			errorCount++
+			for target := range desired {
+				if target.kind == kind {
+					seen[target] = true
+				}
+			}
+			continue
+		}
+
+		for _, resource := range resources {
+			target := envTarget{kind: kind, name: resource.Name}
+			seen[target] = true
+
+			keys := desired[target]
+			if keepRemoved {
+				if keys == nil {
+					continue
+				}
+				_, current, _ := parseManagedEnv(resource.Config.Environment)
+				for key, value := range keys {
+					current[key] = value
+				}
+				keys = current
+			}
+
+			merged, changes := mergeManagedEnv(resource.Config.Environment, keys)
+			if changes.empty() {
+				t.log.Debug("  Environment of %s is up to date.", target)
+				continue
+			}
+
+			t.log.Info("  Updating environment of %s (%s)...", target, changes)
+			for _, key := range changes.created {
+				t.log.Debug("    + %s", key)
+			}
+			for _, key := range changes.updated {
+				t.log.Debug("    ~ %s", key)
+			}
+			for _, key := range changes.deleted {
+				t.log.Debug("    - %s", key)
+			}
+
+			if err := t.komodoClient.UpdateResourceEnvironment(kind, resource.Name, merged); err != nil {
+				t.log.Error("    Failed to update environment of %s: %v", target, err)
+				errorCount++
+				continue
+			}
+			updates = append(updates, envUpdate{target: target, changes: changes})
+			t.auditEnvironment(target, changes, keys)
+		}
+	}
+
+	for target := range desired {
+		if !seen[target] {
+			t.log.Error("  Komodo %s not found, cannot inject secrets.", target)
+			errorCount++
+		}
+	}
+
+	return updates, errorCount
+}
@@ -0,0 +1,134 @@
+package synchronizer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"komodo-op/internal/komodoclient"
+)
+
+func TestParseManagedEnv(t *testing.T) {
+	environment := strings.Join([]string{
+		"PORT=8080",
+		envBlockBegin,
+		"DB_PASSWORD=s3cr=t",
+		"# not a variable",
+		"API_KEY=",
+		envBlockEnd,
+		"DEBUG=1",
+	}, "\n") + "\n"
+
+	before, current, after := parseManagedEnv(environment)
+	if want := []string{"PORT=8080"}; !reflect.DeepEqual(before, want) {
+		t.Errorf("before = %q, want %q", before, want)
+	}
+	if want := map[string]string{"DB_PASSWORD": "s3cr=t", "API_KEY": ""}; !reflect.DeepEqual(current, want) {
+		t.Errorf("current = %q, want %q", current, want)
+	}
+	if want := []string{"DEBUG=1"}; !reflect.DeepEqual(after, want) {
+		t.Errorf("after = %q, want %q", after, want)
+	}
+}
+
+func TestParseManagedEnvWithoutBlock(t *testing.T) {
+	before, current, after := parseManagedEnv("A=1\nB=2")
+	if !reflect.DeepEqual(before, []string{"A=1", "B=2"}) || len(current) != 0 || after != nil {
+		t.Errorf("parseManagedEnv() = %q, %q, %q", before, current, after)
+	}
+	before, current, after = parseManagedEnv("")
+	if before != nil || len(current) != 0 || after != nil {
+		t.Errorf("parseManagedEnv(\"\") = %q, %q, %q", before, current, after)
+	}
+}
+
+func TestMergeManagedEnv(t *testing.T) {
+	block := func(lines ...string) string {
+		return strings.Join(append(append([]string{envBlockBegin}, lines...), envBlockEnd), "\n")
+	}
+
+	tests := []struct {
+		name        string
+		environment string
+		desired     map[string]string
+		want        string
+		wantChanges envChanges
+	}{
+		{
+			name:        "block appended to an environment",
+			environment: "PORT=8080\n",
+			desired:     map[string]string{"B": "2", "A": "1"},
+			want:        "PORT=8080\n" + block("A=1", "B=2"),
+			wantChanges: envChanges{created: []string{"A", "B"}},
+		},
+		{
+			name:        "block in an empty environment",
+			environment: "",
+			desired:     map[string]string{"A": "1"},
+			want:        block("A=1"),
+			wantChanges: envChanges{created: []string{"A"}},
+		},
+		{
+			name:        "keys created, updated and deleted in place",
+			environment: "PORT=8080\n" + block("A=1", "B=2", "C=3") + "\nDEBUG=1",
+			desired:     map[string]string{"A": "1", "B": "two", "D": "4"},
+			want:        "PORT=8080\n" + block("A=1", "B=two", "D=4") + "\nDEBUG=1",
+			wantChanges: envChanges{created: []string{"D"}, updated: []string{"B"}, deleted: []string{"C"}},
+		},
+		{
+			name:        "block removed without keys",
+			environment: "PORT=8080\n" + block("A=1") + "\nDEBUG=1",
+			desired:     nil,
+			want:        "PORT=8080\nDEBUG=1",
+			wantChanges: envChanges{deleted: []string{"A"}},
+		},
+		{
+			name:        "unchanged environment kept as-is",
+			environment: "PORT=8080\n" + block("B=2", "A=1") + "\n\n",
+			desired:     map[string]string{"A": "1", "B": "2"},
+			want:        "PORT=8080\n" + block("B=2", "A=1") + "\n\n",
+		},
+		{
+			name:        "environment without block nor keys",
+			environment: "PORT=8080\n",
+			desired:     map[string]string{},
+			want:        "PORT=8080\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes := mergeManagedEnv(tt.environment, tt.desired)
+			if got != tt.want {
+				t.Errorf("mergeManagedEnv() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(changes, tt.wantChanges) {
+				t.Errorf("changes = %+v, want %+v", changes, tt.wantChanges)
+			}
+
+			// Merging again changes nothing
+			if again, changes := mergeManagedEnv(got, tt.desired); again != got || !changes.empty() {
+				t.Errorf("second merge = %q, %v, want no change", again, changes)
+			}
+		})
+	}
+}
+
+func TestEnvTargetForTags(t *testing.T) {
+	tests := []struct {
+		tags   []string
+		want   envTarget
+		wantOK bool
+	}{
+		{[]string{"prod", "komodo-stack: web "}, envTarget{kind: komodoclient.ResourceStack, name: "web"}, true},
+		{[]string{"komodo-deployment:api"}, envTarget{kind: komodoclient.ResourceDeployment, name: "api"}, true},
+		{[]string{"komodo-repo:infra"}, envTarget{kind: komodoclient.ResourceRepo, name: "infra"}, true},
+		{[]string{"komodo-stack:", "prod"}, envTarget{}, false},
+		{nil, envTarget{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := envTargetForTags(tt.tags)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("envTargetForTags(%q) = %v, %v, want %v, %v", tt.tags, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
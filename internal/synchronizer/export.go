@@ -0,0 +1,82 @@
+package synchronizer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ExportTOML renders the variables a target would manage as Komodo ResourceSync
+// [[variable]] blocks. Values are left out unless includeValues is set, as a
+// ResourceSync would apply any placeholder as the actual value.
+// An empty target name selects the first configured target.
+func (s *Synchronizer) ExportTOML(targetName string, w io.Writer, includeValues bool) error {
+	t, err := s.findTarget(targetName)
+	if err != nil {
+		return err
+	}
+
+	items, err := s.fetchItems(nil)
+	if err != nil {
+		return fmt.Errorf("failed to get items from 1Password: %w", err)
+	}
+	state := t.buildDesiredState(items)
+
+	header := fmt.Sprintf("Variables managed by komodo-op for target '%s', synced from 1P vault '%s'", t.target.Name, s.cfg.OpVaultUUID)
+	_, err = io.WriteString(w, renderVariables(header, state.variables, s.variableDescription(), includeValues))
+	return err
+}
+
+// renderVariables renders variables as [[variable]] blocks sorted by name,
+// after a header comment. Values are left out unless includeValues is set.
+func renderVariables(header string, desired []desiredVariable, description string, includeValues bool) string {
+	variables := append([]desiredVariable{}, desired...)
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].name < variables[j].name
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", header)
+	if !includeValues {
+		b.WriteString("## Values are left out, export with -include-values to include them\n")
+	}
+	for _, variable := range variables {
+		b.WriteString("\n[[variable]]\n")
+		fmt.Fprintf(&b, "name = %s\n", tomlString(variable.name))
+		if includeValues {
+			fmt.Fprintf(&b, "value = %s\n", tomlString(variable.value))
+		}
+		fmt.Fprintf(&b, "description = %s\n", tomlString(description))
+		fmt.Fprintf(&b, "is_secret = %t\n", variable.isSecret)
+	}
+	return b.String()
+}
+
+// tomlString quotes a value as a TOML basic string.
+func tomlString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package synchronizer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderVariables(t *testing.T) {
+	variables := []desiredVariable{
+		{name: "DB__PASSWORD", value: "hunter2", isSecret: true},
+		{name: "APP__URL", value: "https://example.com", isSecret: false},
+	}
+
+	tests := []struct {
+		name          string
+		includeValues bool
+		want          string
+	}{
+		{
+			name: "values left out",
+			want: `## Header
+## Values are left out, export with -include-values to include them
+
+[[variable]]
+name = "APP__URL"
+description = "managed"
+is_secret = false
+
+[[variable]]
+name = "DB__PASSWORD"
+description = "managed"
+is_secret = true
+`,
+		},
+		{
+			name:          "values included",
+			includeValues: true,
+			want: `## Header
+
+[[variable]]
+name = "APP__URL"
+value = "https://example.com"
+description = "managed"
+is_secret = false
+
+[[variable]]
+name = "DB__PASSWORD"
+value = "hunter2"
+description = "managed"
+is_secret = true
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderVariables("Header", variables, "managed", tt.includeValues)
+			if got != tt.want {
+				t.Errorf("renderVariables() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !tt.includeValues && strings.Contains(got, "hunter2") {
+				t.Errorf("renderVariables() leaked a value without includeValues")
+			}
+		})
+	}
+
+	// Sorting must not reorder the desired state itself
+	if variables[0].name != "DB__PASSWORD" {
+		t.Errorf("renderVariables() reordered its input")
+	}
+}
+
+func TestTOMLString(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{`plain`, `"plain"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\path`, `"C:\\path"`},
+		{"line1\nline2\r\tend", `"line1\nline2\r\tend"`},
+		{"bell\x07", `"bell\u0007"`},
+		{"ünïcode", `"ünïcode"`},
+	}
+	for _, tt := range tests {
+		if got := tomlString(tt.value); got != tt.want {
+			t.Errorf("tomlString(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package synchronizer
+
+// runPostSyncHooks triggers the configured post-sync Procedure and Action when
+// at least one variable changed. Only variable names are passed on, never values.
+// Returns the number of errors encountered.
+func (t *targetSync) runPostSyncHooks(changedNames []string) int {
+	if t.cfg.PostSyncProcedure == "" && t.cfg.PostSyncAction == "" {
+		return 0
+	}
+	if len(changedNames) == 0 {
+		t.log.Debug("No variables changed, skipping post-sync executions.")
+		return 0
+	}
+
+	errorCount := 0
+	if t.cfg.PostSyncProcedure != "" {
+		// Procedures take no arguments, the changed names are only logged.
+		t.log.Info("Running post-sync procedure '%s' for %d changed variables...", t.cfg.PostSyncProcedure, len(changedNames))
+		if err := t.komodoClient.RunProcedure(t.cfg.PostSyncProcedure); err != nil {
+			t.log.Error("  Failed to run post-sync procedure: %v", err)
+			errorCount++
+		}
+	}
+	if t.cfg.PostSyncAction != "" {
+		t.log.Info("Running post-sync action '%s' for %d changed variables...", t.cfg.PostSyncAction, len(changedNames))
+		args := map[string]interface{}{"changed_variables": changedNames}
+		if err := t.komodoClient.RunAction(t.cfg.PostSyncAction, args); err != nil {
+			t.log.Error("  Failed to run post-sync action: %v", err)
+			errorCount++
+		}
+	}
+	return errorCount
+}
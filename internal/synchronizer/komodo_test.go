@@ -0,0 +1,181 @@
+package synchronizer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/komodoclient"
+	"komodo-op/internal/logging"
+)
+
+// fakeKomodo is an in-memory Komodo API recording the writes made to it.
+type fakeKomodo struct {
+	mu         sync.Mutex
+	variables  map[string]komodoclient.VariableResponse
+	accounts   map[komodoclient.AccountType][]komodoclient.Account
+	resources  map[komodoclient.ResourceType][]komodoclient.Resource
+	hideTokens bool     // Accounts are listed without their token
+	writes     []string // Types of the write requests received, in order
+}
+
+func newFakeKomodo(t *testing.T) (*fakeKomodo, *komodoclient.Client) {
+	t.Helper()
+	k := &fakeKomodo{
+		variables: make(map[string]komodoclient.VariableResponse),
+		accounts:  make(map[komodoclient.AccountType][]komodoclient.Account),
+		resources: make(map[komodoclient.ResourceType][]komodoclient.Resource),
+	}
+	server := httptest.NewServer(k)
+	t.Cleanup(server.Close)
+	client := komodoclient.NewClient(server.Client(), config.KomodoTarget{Name: "test", Host: server.URL})
+	return k, client
+}
+
+// newTestTarget returns a target syncing to client with the given configuration.
+func newTestTarget(cfg *config.Config, client *komodoclient.Client) *targetSync {
+	s := &Synchronizer{cfg: cfg, log: logging.With(), versions: make(map[string]itemVersion)}
+	t := &targetSync{Synchronizer: s, target: client.Target(), komodoClient: client, client: client, log: logging.With()}
+	s.targets = []*targetSync{t}
+	return t
+}
+
+func (k *fakeKomodo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var request struct {
+		Type   string          `json:"type"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var params struct {
+		Name        string                      `json:"name"`
+		Value       string                      `json:"value"`
+		Description string                      `json:"description"`
+		IsSecret    bool                        `json:"is_secret"`
+		ID          string                      `json:"id"`
+		Account     komodoclient.AccountParams  `json:"account"`
+		Config      komodoclient.ResourceConfig `json:"config"`
+	}
+	json.Unmarshal(request.Params, &params)
+	if r.URL.Path == "/write" {
+		k.writes = append(k.writes, request.Type)
+	}
+
+	respond := func(body interface{}) { json.NewEncoder(w).Encode(body) }
+	variable := k.variables[params.Name]
+	var accountType komodoclient.AccountType
+	for _, kind := range accountTypes {
+		if strings.Contains(request.Type, string(kind)) {
+			accountType = kind
+		}
+	}
+	switch {
+	case request.Type == "GetVariable":
+		if _, ok := k.variables[params.Name]; !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			respond(map[string]string{"error": "no variable found with name " + params.Name})
+			return
+		}
+		respond(variable)
+	case request.Type == "ListVariables":
+		list := []komodoclient.VariableResponse{}
+		for _, v := range k.variables {
+			list = append(list, v)
+		}
+		respond(list)
+	case request.Type == "CreateVariable":
+		k.variables[params.Name] = komodoclient.VariableResponse{Name: params.Name, Value: params.Value, Description: params.Description, IsSecret: params.IsSecret}
+		respond(nil)
+	case request.Type == "UpdateVariableValue":
+		variable.Value = params.Value
+		k.variables[params.Name] = variable
+		respond(nil)
+	case request.Type == "UpdateVariableDescription":
+		variable.Description = params.Description
+		k.variables[params.Name] = variable
+		respond(nil)
+	case request.Type == "UpdateVariableIsSecret":
+		variable.IsSecret = params.IsSecret
+		k.variables[params.Name] = variable
+		respond(nil)
+	case request.Type == "DeleteVariable":
+		delete(k.variables, params.Name)
+		respond(nil)
+	case strings.HasPrefix(request.Type, "ListFull"):
+		kind := komodoclient.ResourceType(strings.TrimSuffix(strings.TrimPrefix(request.Type, "ListFull"), "s"))
+		list := []map[string]interface{}{}
+		for _, resource := range k.resources[kind] {
+			list = append(list, map[string]interface{}{"name": resource.Name, "config": resource.Config})
+		}
+		respond(list)
+	case accountType != "":
+		k.serveAccounts(request.Type, accountType, params.ID, params.Account, respond)
+	case strings.HasPrefix(request.Type, "Update"):
+		kind := komodoclient.ResourceType(strings.TrimPrefix(request.Type, "Update"))
+		for i, resource := range k.resources[kind] {
+			if resource.Name == params.ID {
+				k.resources[kind][i].Config = params.Config
+			}
+		}
+		respond(nil)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		respond(map[string]string{"error": "unsupported request " + request.Type})
+	}
+}
+
+func (k *fakeKomodo) serveAccounts(requestType string, kind komodoclient.AccountType, id string, params komodoclient.AccountParams, respond func(interface{})) {
+	account := komodoclient.Account{ID: komodoclient.MongoID{OID: id}, Domain: params.Domain, Username: params.Username, Token: params.Token}
+	if params.HTTPS != nil {
+		account.HTTPS = *params.HTTPS
+	}
+	switch {
+	case strings.HasPrefix(requestType, "List"):
+		list := []komodoclient.Account{}
+		for _, account := range k.accounts[kind] {
+			if k.hideTokens {
+				account.Token = ""
+			}
+			list = append(list, account)
+		}
+		respond(list)
+	case strings.HasPrefix(requestType, "Create"):
+		account.ID.OID = account.Username + "@" + account.Domain
+		k.accounts[kind] = append(k.accounts[kind], account)
+		respond(nil)
+	case strings.HasPrefix(requestType, "Update"):
+		for i := range k.accounts[kind] {
+			if k.accounts[kind][i].ID.OID == id {
+				k.accounts[kind][i] = account
+			}
+		}
+		respond(nil)
+	case strings.HasPrefix(requestType, "Delete"):
+		var kept []komodoclient.Account
+		for _, existing := range k.accounts[kind] {
+			if existing.ID.OID != id {
+				kept = append(kept, existing)
+			}
+		}
+		k.accounts[kind] = kept
+		respond(nil)
+	}
+}
+
+// takeWrites returns the write requests received since the last call.
+func (k *fakeKomodo) takeWrites() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	writes := k.writes
+	k.writes = nil
+	return writes
+}
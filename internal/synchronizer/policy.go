@@ -0,0 +1,27 @@
+package synchronizer
+
+import (
+	"komodo-op/internal/config"
+	"komodo-op/internal/opclient"
+)
+
+// isSecretVariable decides whether a variable synced from a field is marked as
+// secret in Komodo. Explicit overrides win, then PASSWORD fields are always
+// secret, then the configured policy applies.
+func (s *Synchronizer) isSecretVariable(name string, field opclient.Field) bool {
+	if isSecret, ok := s.cfg.SecretOverrides[name]; ok {
+		return isSecret
+	}
+	if s.cfg.SecretPolicy != config.SecretPolicyFieldType {
+		return true
+	}
+	if field.Purpose == "PASSWORD" {
+		return true
+	}
+	for _, fieldType := range s.cfg.NonSecretFieldTypes {
+		if field.Type == fieldType {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package synchronizer
+
+import (
+	"fmt"
+	"strings"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/komodoclient"
+)
+
+// Resource types that can be redeployed after a secret change, in processing order.
+var redeployResourceTypes = []komodoclient.ResourceType{
+	komodoclient.ResourceStack,
+	komodoclient.ResourceDeployment,
+}
+
+// redeployAllowed reports whether a resource passes the configured allowlist.
+// Entries match either the bare resource name or "kind:name".
+func (t *targetSync) redeployAllowed(target envTarget) bool {
+	if len(t.cfg.RedeployAllowlist) == 0 {
+		return true
+	}
+	qualified := fmt.Sprintf("%s:%s", strings.ToLower(string(target.kind)), target.name)
+	for _, entry := range t.cfg.RedeployAllowlist {
+		if entry == target.name || entry == qualified {
+			return true
+		}
+	}
+	return false
+}
+
+// findAffectedResources returns the resources that reference one of the changed
+// variables or whose managed environment was rewritten, with the reason for each.
+func (t *targetSync) findAffectedResources(changedNames []string, envUpdates []envUpdate) ([]envTarget, map[envTarget]string, int) {
+	changed := make(map[string]bool, len(changedNames))
+	for _, name := range changedNames {
+		changed[name] = true
+	}
+	envUpdated := make(map[envTarget]bool, len(envUpdates))
+	for _, update := range envUpdates {
+		envUpdated[update.target] = true
+	}
+
+	var affected []envTarget
+	reasons := make(map[envTarget]string)
+	errorCount := 0
+	for _, kind := range redeployResourceTypes {
+		resources, err := t.komodoClient.ListResources(kind)
+		if err != nil {
+			t.log.Error("Failed to list Komodo %s, cannot check them for redeploy: %v", kind.Plural(), err)
+			errorCount++
+			continue
+		}
+
+		for _, resource := range resources {
+			target := envTarget{kind: kind, name: resource.Name}
+			var matched []string
+			for _, ref := range extractVariableRefs(string(resource.RawConfig)) {
+				if changed[ref] {
+					matched = append(matched, ref)
+				}
+			}
+
+			switch {
+			case len(matched) > 0:
+				reasons[target] = "references " + strings.Join(matched, ", ")
+			case envUpdated[target]:
+				reasons[target] = "managed environment changed"
+			default:
+				continue
+			}
+			affected = append(affected, target)
+		}
+	}
+	return affected, reasons, errorCount
+}
+
+// redeployAffected redeploys, or lists in dry-run mode, the resources affected by
+// this run's changes, according to REDEPLOY_MODE.
+// Returns the number of errors encountered.
+func (t *targetSync) redeployAffected(changedNames []string, envUpdates []envUpdate) int {
+	if t.cfg.RedeployMode == config.RedeployOff {
+		return 0
+	}
+	if len(changedNames) == 0 && len(envUpdates) == 0 {
+		t.log.Info("No secrets changed, nothing to redeploy.")
+		return 0
+	}
+
+	t.log.Info("Looking for Komodo resources affected by %d changed secrets...", len(changedNames))
+	affected, reasons, errorCount := t.findAffectedResources(changedNames, envUpdates)
+
+	var allowed []envTarget
+	for _, target := range affected {
+		if !t.redeployAllowed(target) {
+			t.log.Info("  Skipping %s (%s): not in REDEPLOY_ALLOWLIST.", target, reasons[target])
+			continue
+		}
+		allowed = append(allowed, target)
+	}
+
+	if len(allowed) == 0 {
+		t.log.Info("Finished redeploy phase. No resources to redeploy.")
+		return errorCount
+	}
+
+	if t.cfg.RedeployMode == config.RedeployDryRun {
+		for _, target := range allowed {
+			t.log.Info("  [dry-run] Would redeploy %s (%s).", target, reasons[target])
+		}
+		if t.cfg.RedeployProcedure != "" {
+			t.log.Info("  [dry-run] Would run procedure '%s' instead of deploying each resource.", t.cfg.RedeployProcedure)
+		}
+		t.log.Info("Finished redeploy phase (dry-run). Affected resources: %d", len(allowed))
+		return errorCount
+	}
+
+	if t.cfg.RedeployProcedure != "" {
+		t.log.Info("  Running procedure '%s' for %d affected resources...", t.cfg.RedeployProcedure, len(allowed))
+		if err := t.komodoClient.RunProcedure(t.cfg.RedeployProcedure); err != nil {
+			t.log.Error("    Failed to run redeploy procedure: %v", err)
+			errorCount++
+		}
+		t.log.Info("Finished redeploy phase. Errors: %d", errorCount)
+		return errorCount
+	}
+
+	deployedCount := 0
+	for _, target := range allowed {
+		t.log.Info("  Redeploying %s (%s)...", target, reasons[target])
+		var err error
+		switch target.kind {
+		case komodoclient.ResourceStack:
+			err = t.komodoClient.DeployStack(target.name)
+		case komodoclient.ResourceDeployment:
+			err = t.komodoClient.Deploy(target.name)
+		}
+		if err != nil {
+			t.log.Error("    Failed to redeploy %s: %v", target, err)
+			errorCount++
+			continue
+		}
+		deployedCount++
+	}
+	t.log.Info("Finished redeploy phase. Redeployed: %d, Errors: %d", deployedCount, errorCount)
+	return errorCount
+}
@@ -0,0 +1,116 @@
+package synchronizer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"komodo-op/internal/komodoclient"
+)
+
+// Matches Komodo variable interpolation, e.g. [[DB_PASSWORD]].
+var variableRefRegex = regexp.MustCompile(`\[\[\s*([A-Za-z0-9_]+)\s*\]\]`)
+
+// extractVariableRefs returns the sorted, de-duplicated variable names referenced in text.
+func extractVariableRefs(text string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, match := range variableRefRegex.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			refs = append(refs, match[1])
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Resource types scanned for variable references, in processing order.
+var referenceResourceTypes = []komodoclient.ResourceType{
+	komodoclient.ResourceStack,
+	komodoclient.ResourceDeployment,
+	komodoclient.ResourceBuild,
+	komodoclient.ResourceRepo,
+}
+
+// DanglingReference is a variable referenced by resources but not defined in Komodo.
+type DanglingReference struct {
+	Variable  string   `json:"variable"`
+	Resources []string `json:"resources"` // e.g. "stack:my-app"
+}
+
+// ReferenceReport describes how variables are referenced across Komodo resources.
+type ReferenceReport struct {
+	Dangling []DanglingReference `json:"dangling"` // Referenced but not defined, deploys will break
+	Unused   []string            `json:"unused"`   // Managed by this tool but never referenced
+}
+
+// CheckReferences scans all Stacks, Deployments, Builds and Repos of a target for
+// [[VAR]] references and compares them with the variables defined there.
+// An empty target name selects the first configured target.
+func (s *Synchronizer) CheckReferences(targetName string) (*ReferenceReport, error) {
+	t, err := s.findTarget(targetName)
+	if err != nil {
+		return nil, err
+	}
+	return t.checkReferences()
+}
+
+func (t *targetSync) checkReferences() (*ReferenceReport, error) {
+	komodoVars, err := t.komodoClient.ListVariables()
+	if err != nil {
+		return nil, err
+	}
+
+	defined := make(map[string]bool, len(komodoVars))
+	for name := range komodoVars {
+		defined[name] = true
+	}
+	secrets, err := t.komodoClient.ListSecrets()
+	if err != nil {
+		t.log.Debug("Could not list Komodo core secrets, references to them will be reported as dangling: %v", err)
+	}
+	for _, name := range secrets {
+		defined[name] = true
+	}
+
+	referencedBy := make(map[string][]string)
+	for _, kind := range referenceResourceTypes {
+		resources, err := t.komodoClient.ListResources(kind)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			label := fmt.Sprintf("%s:%s", strings.ToLower(string(kind)), resource.Name)
+			for _, ref := range extractVariableRefs(string(resource.RawConfig)) {
+				referencedBy[ref] = append(referencedBy[ref], label)
+			}
+		}
+	}
+
+	report := &ReferenceReport{
+		Dangling: []DanglingReference{},
+		Unused:   []string{},
+	}
+	for name, resources := range referencedBy {
+		if !defined[name] {
+			report.Dangling = append(report.Dangling, DanglingReference{Variable: name, Resources: resources})
+		}
+	}
+	sort.Slice(report.Dangling, func(i, j int) bool {
+		return report.Dangling[i].Variable < report.Dangling[j].Variable
+	})
+
+	for name, details := range komodoVars {
+		if name == accountsStateVariable {
+			continue
+		}
+		if strings.Contains(details.Description, managedByMarker) && len(referencedBy[name]) == 0 {
+			report.Unused = append(report.Unused, name)
+		}
+	}
+	sort.Strings(report.Unused)
+
+	return report, nil
+}
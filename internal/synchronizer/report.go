@@ -0,0 +1,95 @@
+package synchronizer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// TargetReport summarizes a run against one Komodo target.
+type TargetReport struct {
+	Target              string   `json:"target"`
+	Created             int      `json:"created"`
+	Updated             int      `json:"updated"`
+	Corrected           int      `json:"corrected"` // Only description or is_secret drifted
+	Unchanged           int      `json:"unchanged"`
+	Deleted             int      `json:"deleted"`
+	Failed              int      `json:"failed"`                        // Variables that failed to be created, updated or deleted
+	DeletionsBlocked    int      `json:"deletions_blocked,omitempty"`   // Orphans kept because they exceeded the target's MAX_DELETIONS
+	DeletionsDeferred   int      `json:"deletions_deferred,omitempty"`  // Orphans kept during a freeze window
+	ExecutionsDeferred  int      `json:"executions_deferred,omitempty"` // Changes whose redeploys and post-sync executions wait for the end of a freeze window
+	ChangedVariables    []string `json:"changed_variables"`
+	DeletedVariables    []string `json:"deleted_variables"`
+	EnvironmentsUpdated []string `json:"environments_updated"`
+	AccountsChanged     int      `json:"accounts_changed"`
+	AccountsDeleted     int      `json:"accounts_deleted"`
+	Skipped             int      `json:"skipped"`
+	Excluded            int      `json:"excluded"`
+	Errors              int      `json:"errors"`
+}
+
+// What started a run, as recorded in Report.Trigger.
+const (
+	TriggerStartup  = "startup"  // Initial run of the daemon
+	TriggerSchedule = "schedule" // Scheduled run of the daemon
+	TriggerCatchUp  = "catch-up" // Run at the end of a freeze window that deferred writes
+	TriggerPoll     = "poll"     // Run of the items found changed by CHANGE_POLL_INTERVAL
+	TriggerAPI      = "api"      // Run requested through the control API
+	TriggerOneOff   = "one-off"  // Run outside of daemon mode
+)
+
+// Report summarizes a synchronization run. It never contains secret values.
+type Report struct {
+	RunID      string          `json:"run_id"`
+	Trigger    string          `json:"trigger,omitempty"`  // What started the run, one of the Trigger constants
+	TraceID    string          `json:"trace_id,omitempty"` // Set when tracing is enabled
+	Partial    bool            `json:"partial,omitempty"`  // Only some items were synchronized, nothing was deleted
+	Items      []string        `json:"items,omitempty"`    // Items requested by a partial run
+	Frozen     bool            `json:"frozen,omitempty"`   // Ran during a freeze window, deletions were deferred
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Targets    []*TargetReport `json:"targets"`
+	Errors     int             `json:"errors"` // Total errors, including failures to read from 1Password
+}
+
+// Duration returns how long the run took.
+func (r *Report) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Outcome returns "success" for a run without errors, "failure" otherwise.
+func (r *Report) Outcome() string {
+	if r.Errors > 0 {
+		return "failure"
+	}
+	return "success"
+}
+
+// DeletionsBlocked reports whether MAX_DELETIONS prevented deletions on any target.
+func (r *Report) DeletionsBlocked() bool {
+	for _, target := range r.Targets {
+		if target.DeletionsBlocked > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Changes returns the number of variables, environments and accounts changed across all targets.
+func (r *Report) Changes() int {
+	changes := 0
+	for _, target := range r.Targets {
+		changes += target.Created + target.Updated + target.Deleted +
+			len(target.EnvironmentsUpdated) + target.AccountsChanged + target.AccountsDeleted
+	}
+	return changes
+}
+
+// newRunID returns a random identifier correlating the logs and reports of a run.
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
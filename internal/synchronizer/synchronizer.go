@@ -3,12 +3,18 @@ package synchronizer
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"komodo-op/internal/config"
+	"komodo-op/internal/health"
 	"komodo-op/internal/komodoclient"
 	"komodo-op/internal/logging"
+	"komodo-op/internal/metrics"
 	"komodo-op/internal/opclient"
+	"komodo-op/internal/tracing"
 )
 
 var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
@@ -19,17 +25,54 @@ const managedByMarker = "1Password-Sync:"
 
 // Synchronizer handles the core logic of syncing secrets from 1Password to Komodo.
 type Synchronizer struct {
-	opClient     *opclient.Client
-	komodoClient *komodoclient.Client
-	cfg          *config.Config // Keep a reference for vault UUID etc.
+	opClient *opclient.Client
+	targets  []*targetSync
+	cfg      *config.Config  // Keep a reference for vault UUID etc.
+	log      *logging.Logger // Carries the run_id of the current run
+	runID    string          // ID of the current run
+	partial  bool            // Current run only covers some items, nothing is deleted
+	frozen   bool            // Deletions are deferred by a freeze window, see SetDeletionsFrozen
+
+	versions map[string]itemVersion // Versions of the items as last fetched, by item ID
+}
+
+// itemVersion identifies a revision of a 1Password item.
+type itemVersion struct {
+	version   int
+	updatedAt time.Time
 }
 
-// New creates a new Synchronizer.
-func New(opClient *opclient.Client, komodoClient *komodoclient.Client, cfg *config.Config) *Synchronizer {
-	return &Synchronizer{
-		opClient:     opClient,
-		komodoClient: komodoClient,
-		cfg:          cfg,
+// New creates a new Synchronizer applying 1Password items to every given Komodo client.
+func New(opClient *opclient.Client, komodoClients []*komodoclient.Client, cfg *config.Config) *Synchronizer {
+	s := &Synchronizer{
+		opClient: opClient,
+		cfg:      cfg,
+		log:      logging.With(),
+		versions: make(map[string]itemVersion),
+	}
+	for _, komodoClient := range komodoClients {
+		s.targets = append(s.targets, &targetSync{
+			Synchronizer: s,
+			target:       komodoClient.Target(),
+			komodoClient: komodoClient,
+			client:       komodoClient,
+			log:          logging.With("target", komodoClient.Target().Name),
+		})
+	}
+	return s
+}
+
+// Inherit carries the state a synchronizer keeps between runs over from the one
+// it replaces on reload: the item versions RunChanged compares against, and the
+// executions deferred by a freeze window on targets kept by name.
+func (s *Synchronizer) Inherit(previous *Synchronizer) {
+	s.versions = previous.versions
+	for _, t := range s.targets {
+		for _, old := range previous.targets {
+			if old.target.Name == t.target.Name {
+				t.deferredNames, t.deferredEnvs = old.deferredNames, old.deferredEnvs
+			}
+		}
 	}
 }
 
@@ -76,124 +119,441 @@ func sanitizeNameForLog(name string) string {
 	return strings.Join(parts, "__")
 }
 
-// syncKomodoSecret ensures a secret exists in Komodo with the correct value.
-func (s *Synchronizer) syncKomodoSecret(name, value string) error {
-	logging.Debug("Checking existence of Komodo variable '%s'", name)
-	_, found, err := s.komodoClient.GetVariable(name)
+// syncAction describes what syncKomodoSecret did to a variable.
+type syncAction string
+
+const (
+	actionCreated   syncAction = "created"
+	actionUpdated   syncAction = "updated"   // Value changed
+	actionCorrected syncAction = "corrected" // Only description or is_secret drifted
+	actionUnchanged syncAction = "unchanged"
+)
+
+// variableDescription returns the description of variables managed by this tool.
+func (s *Synchronizer) variableDescription() string {
+	return fmt.Sprintf("%s Synced from 1P vault '%s'", managedByMarker, s.cfg.OpVaultUUID)
+}
+
+// syncKomodoSecret ensures a secret exists in Komodo with the correct value,
+// description and secret flag.
+func (t *targetSync) syncKomodoSecret(name, value string, isSecret bool) (syncAction, error) {
+	log := t.log.With("variable", name)
+	log.Debug("Checking existence of Komodo variable '%s'", name)
+	existing, found, err := t.komodoClient.GetVariable(name)
 
 	if err != nil {
-		return fmt.Errorf("failed during existence check for variable '%s': %w", name, err)
+		return "", fmt.Errorf("failed during existence check for variable '%s': %w", name, err)
 	}
 
-	if found {
-		logging.Info("  Variable '%s' exists, attempting update.", sanitizeNameForLog(name))
-		return s.komodoClient.UpdateVariableValue(name, value)
-	} else {
-		logging.Info("  Variable '%s' does not exist, attempting create.", sanitizeNameForLog(name))
-		description := fmt.Sprintf("%s Synced from 1P vault '%s'", managedByMarker, s.cfg.OpVaultUUID)
-		return s.komodoClient.CreateVariable(name, value, description)
+	description := t.variableDescription()
+	if !found {
+		log.With("op", actionCreated).Info("  Variable '%s' does not exist, attempting create.", sanitizeNameForLog(name))
+		return actionCreated, t.komodoClient.CreateVariable(name, value, description, isSecret)
 	}
+
+	action := actionUnchanged
+	if existing.Value != value {
+		log.With("op", actionUpdated).Info("  Variable '%s' exists, attempting update.", sanitizeNameForLog(name))
+		if err := t.komodoClient.UpdateVariableValue(name, value); err != nil {
+			return "", err
+		}
+		action = actionUpdated
+	}
+	if existing.Description != description {
+		log.With("op", actionCorrected).Info("  Variable '%s' description drifted, correcting.", sanitizeNameForLog(name))
+		if err := t.komodoClient.UpdateVariableDescription(name, description); err != nil {
+			return "", err
+		}
+		if action == actionUnchanged {
+			action = actionCorrected
+		}
+	}
+	if existing.IsSecret != isSecret {
+		log.With("op", actionCorrected).Info("  Variable '%s' is_secret drifted (%t), setting it to %t.", sanitizeNameForLog(name), existing.IsSecret, isSecret)
+		if err := t.komodoClient.UpdateVariableIsSecret(name, isSecret); err != nil {
+			return "", err
+		}
+		if action == actionUnchanged {
+			action = actionCorrected
+		}
+	}
+	if action == actionUnchanged {
+		log.With("op", actionUnchanged).Info("  Variable '%s' is up to date.", sanitizeNameForLog(name))
+	}
+	return action, nil
 }
 
-// Run executes the synchronization process.
-// Returns the total number of errors encountered.
-func (s *Synchronizer) Run() int {
-	logging.Info("Fetching items from 1Password vault '%s'...", s.cfg.OpVaultUUID)
+// listItems lists the items of the vaults, without their fields.
+func (s *Synchronizer) listItems(span *tracing.Span) ([]opclient.Item, error) {
+	s.log.Info("Fetching items from 1Password vault '%s'...", s.cfg.OpVaultUUID)
+	listSpan := span.StartClient("1password list items", "op.vault_id", s.cfg.OpVaultUUID)
 	items, err := s.opClient.GetItems()
-	if (err != nil) {
-		logging.Error("Failed to get items from 1Password: %v", err)
-		return 1 // Indicate failure
+	listSpan.SetError(err)
+	listSpan.End()
+	return items, err
+}
+
+// fetchDetails retrieves the details of the given items.
+// Items whose details cannot be fetched are logged and skipped.
+func (s *Synchronizer) fetchDetails(span *tracing.Span, items []opclient.Item) []*opclient.ItemDetail {
+	details := make([]*opclient.ItemDetail, 0, len(items))
+	for _, item := range items {
+		log := s.log.With("item_id", item.ID)
+		log.Debug("Fetching 1P item: '%s' (ID: %s)", item.Title, item.ID)
+		itemSpan := span.StartClient("1password fetch item", "op.vault_id", item.Vault.ID, "op.item_id", item.ID)
+		itemDetail, err := s.opClient.GetItemDetails(item.Vault.ID, item.ID)
+		itemSpan.SetError(err)
+		itemSpan.End()
+		if err != nil {
+			log.Error("Failed to get details for item '%s' (%s): %v", item.Title, item.ID, err)
+			continue // Skip item
+		}
+		details = append(details, itemDetail)
 	}
+	return details
+}
 
-	if len(items) == 0 {
-		logging.Info("No items found in vault '%s'. Exiting.", s.cfg.OpVaultUUID)
-		return 0 // No errors, but nothing to do
+// fetchItems retrieves the details of every item in the vault.
+func (s *Synchronizer) fetchItems(span *tracing.Span) ([]*opclient.ItemDetail, error) {
+	items, err := s.listItems(span)
+	if err != nil {
+		return nil, err
 	}
+	return s.fetchDetails(span, items), nil
+}
+
+// itemLister lists the items of the vaults a run starts from.
+type itemLister func(span *tracing.Span) ([]opclient.Item, error)
+
+// listedItems returns a lister of items already listed, sparing a second listing.
+func listedItems(items []opclient.Item) itemLister {
+	return func(*tracing.Span) ([]opclient.Item, error) { return items, nil }
+}
+
+// itemSelector picks the items a partial run covers among those listed in the vault.
+type itemSelector func(items []opclient.Item) ([]opclient.Item, error)
+
+// selectItems returns a selector picking items by ID or title. Every reference must match an item.
+func selectItems(refs []string) itemSelector {
+	return func(items []opclient.Item) ([]opclient.Item, error) {
+		var selected []opclient.Item
+		var unknown []string
+		for _, ref := range refs {
+			found := false
+			for _, item := range items {
+				if item.ID == ref || item.Title == ref {
+					selected = append(selected, item)
+					found = true
+				}
+			}
+			if !found {
+				unknown = append(unknown, fmt.Sprintf("'%s'", ref))
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("no item with ID or title %s", strings.Join(unknown, ", "))
+		}
+		return selected, nil
+	}
+}
+
+// Run executes the synchronization process. Items are fetched from 1Password
+// once and then applied to each Komodo target independently.
+// Returns a report of the run; its Errors field holds the total number of errors encountered.
+func (s *Synchronizer) Run() *Report {
+	return s.run(s.listItems, nil)
+}
+
+// SetDeletionsFrozen defers the deletions of the following runs while a freeze
+// window is active: they create and update, but keep orphaned variables,
+// environment keys and accounts until a run outside the window.
+func (s *Synchronizer) SetDeletionsFrozen(frozen bool) {
+	s.frozen = frozen
+}
+
+// RunItems synchronizes only the given items, referenced by ID or title. Such a
+// partial run creates and updates, but never deletes: the variables, environment
+// keys and accounts of other items are left untouched.
+func (s *Synchronizer) RunItems(refs []string) *Report {
+	report := s.run(s.listItems, selectItems(refs))
+	report.Items = refs
+	return report
+}
 
-	expectedKomodoNames := make(map[string]bool)
-	type secretToSync struct {
-		name  string
-		value string
+// RunChanged lists the items of the vault and synchronizes only those added or
+// changed since they were last fetched, comparing their version and update time.
+// As only a full run deletes, one is made instead if items were removed.
+// The run starts from the items listed here rather than listing them again.
+// Returns nil without running if no item changed, or if listing the items failed.
+func (s *Synchronizer) RunChanged() *Report {
+	s.log = logging.With()
+	items, err := s.opClient.GetItems()
+	if err != nil {
+		s.log.Error("Failed to poll items from 1Password: %v", err)
+		return nil
 	}
-	secretsToSync := []secretToSync{}
 
-	logging.Info("Processing %d items from 1Password...", len(items))
-	skipped1PCount := 0
+	var changed []string
+	listedIDs := make(map[string]bool, len(items))
 	for _, item := range items {
-		logging.Debug("Processing 1P item: '%s' (ID: %s)", item.Title, item.ID)
-		itemDetail, err := s.opClient.GetItemDetails(item.ID)
-		if err != nil {
-			logging.Error("Failed to get details for item '%s' (%s): %v", item.Title, item.ID, err)
-			continue // Skip item
+		listedIDs[item.ID] = true
+		if cached, ok := s.versions[item.ID]; !ok || cached.version != item.Version || !cached.updatedAt.Equal(item.UpdatedAt) {
+			s.log.With("item_id", item.ID).Info("Item '%s' changed since it was last fetched.", item.Title)
+			changed = append(changed, item.ID)
+		}
+	}
+	for id := range s.versions {
+		if !listedIDs[id] {
+			s.log.With("item_id", id).Info("Item %s was removed, running a full synchronization.", id)
+			return s.run(listedItems(items), nil)
+		}
+	}
+
+	if len(changed) == 0 {
+		s.log.Debug("No item changed since the last poll.")
+		return nil
+	}
+	report := s.run(listedItems(items), selectItems(changed))
+	report.Items = changed
+	return report
+}
+
+// run synchronizes all items returned by list, or only those picked by selector.
+func (s *Synchronizer) run(list itemLister, selector itemSelector) *Report {
+	report := &Report{RunID: newRunID(), StartedAt: time.Now(), Targets: []*TargetReport{}, Partial: selector != nil, Frozen: s.frozen}
+	span := tracing.StartTrace("sync run", "run_id", report.RunID)
+	report.TraceID = span.TraceID()
+	s.runID = report.RunID
+	s.partial = report.Partial
+	s.log = logging.With("run_id", report.RunID)
+	if report.TraceID != "" {
+		s.log = s.log.With("trace_id", report.TraceID)
+	}
+	for _, t := range s.targets {
+		t.log = s.log.With("target", t.target.Name)
+	}
+	defer func() {
+		report.FinishedAt = time.Now()
+		span.SetAttributes("errors", strconv.Itoa(report.Errors), "changes", strconv.Itoa(report.Changes()))
+		if report.Errors > 0 {
+			span.SetError(fmt.Errorf("run finished with %d errors", report.Errors))
 		}
+		span.End()
+		metrics.RecordRun(report.Duration(), report.Errors == 0, report.FinishedAt)
+		health.RecordRun(report.FinishedAt, report.Errors)
+		for _, target := range report.Targets {
+			metrics.RecordTargetRun(target.Target, map[string]int{
+				"created": target.Created,
+				"updated": target.Updated,
+				"deleted": target.Deleted,
+				"failed":  target.Failed,
+			})
+		}
+	}()
 
-		if len(itemDetail.Fields) == 0 {
-			logging.Info("  Item '%s' has no fields. Skipping.", item.Title)
-			skipped1PCount++
-			continue
+	listed, err := list(span)
+	if err != nil {
+		s.log.Error("Failed to get items from 1Password: %v", err)
+		report.Errors = 1 // Indicate failure
+		return report
+	}
+	if selector != nil {
+		listed, err = selector(listed)
+		if err != nil {
+			s.log.Error("Cannot select items of partial run: %v", err)
+			report.Errors = 1
+			return report
 		}
+		s.log.Info("Partial run of %d items, nothing will be deleted.", len(listed))
+	}
+	items := s.fetchDetails(span, listed)
+	if selector == nil {
+		s.versions = make(map[string]itemVersion, len(items))
+	}
+	for _, item := range items {
+		s.versions[item.ID] = itemVersion{version: item.Version, updatedAt: item.UpdatedAt}
+	}
 
-		for _, field := range itemDetail.Fields {
-			if field.Label == "" || field.Value == "" {
-				logging.Debug("  Skipping field ID %s in item '%s' (label or value is empty)", field.ID, item.Title)
-				skipped1PCount++
-				continue
-			}
+	if len(items) == 0 {
+		s.log.Info("No items found in vault '%s'. Exiting.", s.cfg.OpVaultUUID)
+		return report // No errors, but nothing to do
+	}
 
-			komodoName := formatKomodoName(itemDetail.Title, field.Label)
-			expectedKomodoNames[komodoName] = true
-			secretsToSync = append(secretsToSync, secretToSync{komodoName, field.Value})
-			logging.Debug("  Added expected Komodo name: %s", komodoName)
+	for _, t := range s.targets {
+		s.log.Info("Synchronizing Komodo target '%s' (%s)...", t.target.Name, t.target.Host)
+		targetSpan := span.Start("sync target", "komodo.target", t.target.Name)
+		targetReport := t.run(items, targetSpan)
+		if targetReport.Errors > 0 {
+			targetSpan.SetError(fmt.Errorf("target finished with %d errors", targetReport.Errors))
 		}
+		targetSpan.End()
+		report.Targets = append(report.Targets, targetReport)
+		report.Errors += targetReport.Errors
 	}
-	logging.Info("Finished processing 1Password items. Found %d secrets to potentially sync. Skipped %d items/fields.", len(secretsToSync), skipped1PCount)
 
-	logging.Info("Starting synchronization (create/update) with Komodo...")
+	if len(s.targets) > 1 {
+		s.log.Info("Synchronization of all targets finished.")
+		for _, target := range report.Targets {
+			s.log.Info("  Target '%s': %d errors", target.Target, target.Errors)
+		}
+	}
+
+	return report
+}
+
+// run applies the items to this target.
+func (t *targetSync) run(items []*opclient.ItemDetail, span *tracing.Span) *TargetReport {
+	report := &TargetReport{
+		Target:              t.target.Name,
+		ChangedVariables:    []string{},
+		DeletedVariables:    []string{},
+		EnvironmentsUpdated: []string{},
+	}
+	base, client := t.log, t.client
+	var phaseSpan *tracing.Span
+	startPhase := func(name string) {
+		phaseSpan.End()
+		phaseSpan = span.Start("phase "+name, "phase", name)
+		t.log = base.With("phase", name)
+		t.komodoClient = client.WithSpan(phaseSpan)
+	}
+	defer func() {
+		phaseSpan.End()
+		t.log, t.komodoClient = base, client
+	}()
+
+	startPhase("process")
+	state := t.buildDesiredState(items)
+	report.Skipped = state.skipped
+	report.Excluded = state.excluded
+	accountErrorCount := state.accountErrors
+
+	startPhase("create_update")
+	t.log.Info("Starting synchronization (create/update) with Komodo...")
 	processedCount := 0
 	createUpdateErrorCount := 0
 
-	for _, secret := range secretsToSync {
-		logging.Info("  Syncing Komodo secret '%s'...", sanitizeNameForLog(secret.name))
-		err := s.syncKomodoSecret(secret.name, secret.value)
+	for _, secret := range state.variables {
+		t.log.Info("  Syncing Komodo secret '%s'...", sanitizeNameForLog(secret.name))
+		action, err := t.syncKomodoSecret(secret.name, secret.value, secret.isSecret)
+		if action != actionUnchanged && err == nil {
+			t.auditVariable(action, secret)
+		}
 		if err != nil {
-			logging.Error("    Failed to sync Komodo secret '%s': %v", sanitizeNameForLog(secret.name), err)
+			t.log.With("variable", secret.name).Error("    Failed to sync Komodo secret '%s': %v", sanitizeNameForLog(secret.name), err)
 			createUpdateErrorCount++
 		} else {
 			processedCount++
+			switch action {
+			case actionCreated:
+				report.Created++
+				report.ChangedVariables = append(report.ChangedVariables, secret.name)
+			case actionUpdated:
+				report.Updated++
+				report.ChangedVariables = append(report.ChangedVariables, secret.name)
+			case actionCorrected:
+				report.Corrected++
+			case actionUnchanged:
+				report.Unchanged++
+			}
 		}
 	}
-	logging.Info("Finished create/update phase. Processed: %d, Errors: %d", processedCount, createUpdateErrorCount)
+	t.log.Info("Finished create/update phase. Processed: %d, Errors: %d", processedCount, createUpdateErrorCount)
 
-	logging.Info("Checking for orphaned Komodo variables managed by this tool...")
-	komodoVars, err := s.komodoClient.ListVariables()
-	if err != nil {
-		logging.Error("Failed to list variables from Komodo, skipping deletion phase: %v", err)
-		// Return total errors accumulated so far, plus 1 for this critical failure
-		return createUpdateErrorCount + 1
+	startPhase("delete")
+	deleteErrorCount, blockedErrorCount, listErrorCount := 0, 0, 0
+	var komodoVars map[string]komodoclient.VariableResponse
+	listed := false
+	if t.partial {
+		t.log.Info("Partial run, skipping deletion of orphaned Komodo variables.")
+	} else {
+		t.log.Info("Checking for orphaned Komodo variables managed by this tool...")
+		var err error
+		if komodoVars, err = t.komodoClient.ListVariables(); err != nil {
+			// The following phases don't need the variables, keep going with them
+			t.log.Error("Failed to list variables from Komodo, skipping deletion phase: %v", err)
+			listErrorCount++
+		} else {
+			listed = true
+		}
 	}
+	if listed {
+		var orphans []string
+		for name, details := range komodoVars {
+			if strings.Contains(details.Description, managedByMarker) && !state.expectedNames[name] {
+				orphans = append(orphans, name)
+			}
+		}
+		sort.Strings(orphans)
 
-	deleteCount := 0
-	deleteErrorCount := 0
-	for name, details := range komodoVars {
-		if strings.Contains(details.Description, managedByMarker) && !expectedKomodoNames[name] {
-			logging.Info("  Found orphaned Komodo variable '%s', attempting delete.", sanitizeNameForLog(name))
-			err := s.komodoClient.DeleteVariable(name)
+		if t.frozen && len(orphans) > 0 {
+			t.log.Warn("Freeze window active, deferring deletion of %d orphaned Komodo variables.", len(orphans))
+			report.DeletionsDeferred = len(orphans)
+			orphans = nil
+		}
+		if maxDeletions := t.target.MaxDeletions; maxDeletions > 0 && len(orphans) > maxDeletions {
+			// A mass deletion usually means a wrong vault or filter rather than intended removals
+			t.log.Error("Found %d orphaned Komodo variables, more than MAX_DELETIONS (%d). Skipping deletion.", len(orphans), maxDeletions)
+			report.DeletionsBlocked = len(orphans)
+			blockedErrorCount++
+			orphans = nil
+		}
+		for _, name := range orphans {
+			log := t.log.With("variable", name, "op", "deleted")
+			log.Info("  Found orphaned Komodo variable '%s', attempting delete.", sanitizeNameForLog(name))
+			err := t.komodoClient.DeleteVariable(name)
 			if err != nil {
-				logging.Error("    Failed to delete Komodo variable '%s': %v", sanitizeNameForLog(name), err)
+				log.Error("    Failed to delete Komodo variable '%s': %v", sanitizeNameForLog(name), err)
 				deleteErrorCount++
 			} else {
-				deleteCount++
+				report.Deleted++
+				report.DeletedVariables = append(report.DeletedVariables, name)
+				t.auditDelete(name)
 			}
 		}
+		t.log.Info("Finished deletion phase. Deleted: %d, Errors: %d", report.Deleted, deleteErrorCount)
 	}
-	logging.Info("Finished deletion phase. Deleted: %d, Errors: %d", deleteCount, deleteErrorCount)
+	report.Failed = createUpdateErrorCount + deleteErrorCount
 
-	logging.Info("Synchronization finished.")
-	logging.Info("  Secrets processed (created/updated): %d", processedCount)
-	logging.Info("  Orphaned secrets deleted: %d", deleteCount)
-	logging.Info("  Items/Fields skipped in 1P: %d", skipped1PCount)
-	totalErrors := createUpdateErrorCount + deleteErrorCount
-	logging.Info("  Total errors encountered: %d", totalErrors)
+	startPhase("environments")
+	t.log.Info("Synchronizing managed resource environments with Komodo...")
+	envUpdates, envErrorCount := t.syncEnvironments(state.environments, t.partial || t.frozen)
+	t.log.Info("Finished environment phase. Resources updated: %d, Errors: %d", len(envUpdates), envErrorCount)
+	for _, update := range envUpdates {
+		report.EnvironmentsUpdated = append(report.EnvironmentsUpdated, update.target.String())
+	}
+
+	startPhase("accounts")
+	t.log.Info("Synchronizing managed accounts with Komodo...")
+	accounts := t.syncAccounts(state.accounts, t.partial || t.frozen)
+	accountErrorCount += accounts.errors
+	report.AccountsChanged = accounts.created + accounts.updated
+	report.AccountsDeleted = accounts.deleted
+	t.log.Info("Finished account phase. Created: %d, Updated: %d, Deleted: %d, Errors: %d", accounts.created, accounts.updated, accounts.deleted, accountErrorCount)
+
+	changedNames, changedEnvs := t.deferExecutions(report, envUpdates)
+	startPhase("redeploy")
+	redeployErrorCount := t.redeployAffected(changedNames, changedEnvs)
+	startPhase("hooks")
+	hookErrorCount := t.runPostSyncHooks(changedNames)
+
+	startPhase("summary")
+
+	t.log.Info("Synchronization of target '%s' finished.", t.target.Name)
+	t.log.Info("  Secrets processed (created/updated): %d", processedCount)
+	t.log.Info("  Secrets changed: %d", len(report.ChangedVariables))
+	t.log.Info("  Secrets with drifted description/is_secret corrected: %d", report.Corrected)
+	t.log.Info("  Orphaned secrets deleted: %d", report.Deleted)
+	t.log.Info("  Resource environments updated: %d", len(envUpdates))
+	for _, update := range envUpdates {
+		t.log.Info("    %s (%s)", update.target, update.changes)
+	}
+	t.log.Info("  Accounts created/updated: %d", report.AccountsChanged)
+	t.log.Info("  Orphaned accounts deleted: %d", report.AccountsDeleted)
+	t.log.Info("  Items/Fields skipped in 1P: %d", state.skipped)
+	t.log.Info("  Items excluded from target: %d", state.excluded)
+	report.Errors = createUpdateErrorCount + listErrorCount + deleteErrorCount + blockedErrorCount + envErrorCount + accountErrorCount + redeployErrorCount + hookErrorCount
+	t.log.Info("  Total errors encountered: %d", report.Errors)
 
-	return totalErrors
+	return report
 }
@@ -0,0 +1,107 @@
+package synchronizer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/komodoclient"
+	"komodo-op/internal/opclient"
+)
+
+func TestInherit(t *testing.T) {
+	previous := &Synchronizer{versions: map[string]itemVersion{"item": {version: 3}}}
+	previous.targets = []*targetSync{
+		{Synchronizer: previous, target: config.KomodoTarget{Name: "prod"}, deferredNames: []string{"A"}},
+		{Synchronizer: previous, target: config.KomodoTarget{Name: "removed"}, deferredNames: []string{"B"}},
+	}
+	next := &Synchronizer{versions: map[string]itemVersion{}}
+	next.targets = []*targetSync{
+		{Synchronizer: next, target: config.KomodoTarget{Name: "prod"}},
+		{Synchronizer: next, target: config.KomodoTarget{Name: "added"}},
+	}
+
+	next.Inherit(previous)
+	if !reflect.DeepEqual(next.versions, previous.versions) {
+		t.Errorf("versions = %v, want %v", next.versions, previous.versions)
+	}
+	if got := next.targets[0].deferredNames; !reflect.DeepEqual(got, []string{"A"}) {
+		t.Errorf("deferred names of kept target = %v, want [A]", got)
+	}
+	if got := next.targets[1].deferredNames; got != nil {
+		t.Errorf("deferred names of added target = %v, want none", got)
+	}
+}
+
+func TestFormatKomodoName(t *testing.T) {
+	tests := []struct {
+		item, field, want string
+	}{
+		{"db", "password", "DB__PASSWORD"},
+		{"My App", "API key", "MY_APP__API_KEY"},
+		{"svc.prod", "user@host", "SVC_PROD__USER_HOST"},
+		{"token", "", "TOKEN"},
+	}
+	for _, tt := range tests {
+		if got := formatKomodoName(tt.item, tt.field); got != tt.want {
+			t.Errorf("formatKomodoName(%q, %q) = %q, want %q", tt.item, tt.field, got, tt.want)
+		}
+	}
+}
+
+// fakeConnect is a 1Password Connect API serving the items of one vault and
+// counting how often they are listed.
+type fakeConnect struct {
+	mu    sync.Mutex
+	items []opclient.ItemDetail
+	lists int
+}
+
+func (c *fakeConnect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if strings.HasSuffix(r.URL.Path, "/items") {
+		c.lists++
+		json.NewEncoder(w).Encode(c.items)
+		return
+	}
+	for _, item := range c.items {
+		if strings.HasSuffix(r.URL.Path, "/items/"+item.ID) {
+			json.NewEncoder(w).Encode(item)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func TestRunChangedListsOnce(t *testing.T) {
+	connect := &fakeConnect{items: []opclient.ItemDetail{
+		{ID: "i1", Title: "DB", Version: 1, Fields: []opclient.Field{{ID: "f1", Label: "password", Value: "v1", Type: "CONCEALED"}}},
+	}}
+	server := httptest.NewServer(connect)
+	t.Cleanup(server.Close)
+	cfg := &config.Config{OpConnectHost: server.URL, OpVaults: []string{"vault"}, OpVaultUUID: "vault", OpVaultID: "vault"}
+	_, komodo := newFakeKomodo(t)
+	s := New(opclient.NewClient(server.Client(), cfg), []*komodoclient.Client{komodo}, cfg)
+
+	if report := s.Run(); report.Errors != 0 || connect.lists != 1 {
+		t.Fatalf("full run = %+v after %d listings, want one listing", report, connect.lists)
+	}
+	if report := s.RunChanged(); report != nil || connect.lists != 2 {
+		t.Fatalf("unchanged poll = %+v after %d listings, want no run", report, connect.lists)
+	}
+
+	connect.items[0].Version = 2
+	report := s.RunChanged()
+	if report == nil || !report.Partial || !reflect.DeepEqual(report.Items, []string{"i1"}) {
+		t.Fatalf("poll after a change = %+v, want a partial run of i1", report)
+	}
+	if connect.lists != 3 {
+		t.Errorf("items listed %d times after a run and 2 polls, want 3", connect.lists)
+	}
+}
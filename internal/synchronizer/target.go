@@ -0,0 +1,116 @@
+package synchronizer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/komodoclient"
+	"komodo-op/internal/logging"
+	"komodo-op/internal/opclient"
+)
+
+// targetSync applies the items fetched from 1Password to a single Komodo instance.
+type targetSync struct {
+	*Synchronizer
+	target       config.KomodoTarget
+	komodoClient *komodoclient.Client // Traces its requests under the current phase during runs
+	client       *komodoclient.Client // Untraced, safe to use concurrently with runs
+	log          *logging.Logger      // Carries the run_id, target and phase of the current run
+
+	// Changes whose redeploys and post-sync executions a freeze window deferred
+	deferredNames []string
+	deferredEnvs  []envUpdate
+}
+
+// findTarget returns the target with the given name, or the first target if the name is empty.
+func (s *Synchronizer) findTarget(name string) (*targetSync, error) {
+	for _, t := range s.targets {
+		if name == "" || t.target.Name == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown Komodo target '%s'", name)
+}
+
+// CheckConnectivity checks that 1Password Connect and every Komodo target are
+// reachable. Returns the error of each dependency by name, nil when reachable.
+func (s *Synchronizer) CheckConnectivity() map[string]error {
+	results := map[string]error{"1password": s.opClient.Ping()}
+	for _, t := range s.targets {
+		results["komodo:"+t.target.Name] = t.client.Ping()
+	}
+	return results
+}
+
+// matchesItem reports whether an include/exclude pattern matches an item.
+// Patterns are globs on the item title, or on its tags when prefixed with "tag:".
+func matchesItem(pattern string, item *opclient.ItemDetail) bool {
+	if tagPattern, isTag := strings.CutPrefix(pattern, "tag:"); isTag {
+		for _, tag := range item.Tags {
+			if ok, _ := path.Match(tagPattern, tag); ok {
+				return true
+			}
+		}
+		return false
+	}
+	ok, _ := path.Match(pattern, item.Title)
+	return ok
+}
+
+// includes reports whether an item is synced to this target.
+func (t *targetSync) includes(item *opclient.ItemDetail) bool {
+	if len(t.target.Include) > 0 {
+		included := false
+		for _, pattern := range t.target.Include {
+			if matchesItem(pattern, item) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, pattern := range t.target.Exclude {
+		if matchesItem(pattern, item) {
+			return false
+		}
+	}
+	return true
+}
+
+// deferExecutions returns the changed variable names and environments a run
+// redeploys and passes to post-sync executions. During a freeze window they are
+// kept instead and nothing is returned; the first run after it gets them along
+// with its own changes.
+func (t *targetSync) deferExecutions(report *TargetReport, envUpdates []envUpdate) ([]string, []envUpdate) {
+	names := report.ChangedVariables
+	if t.frozen {
+		executions := t.cfg.RedeployMode != config.RedeployOff || t.cfg.PostSyncProcedure != "" || t.cfg.PostSyncAction != ""
+		if executions && len(names)+len(envUpdates) > 0 {
+			t.deferredNames = append(t.deferredNames, names...)
+			t.deferredEnvs = append(t.deferredEnvs, envUpdates...)
+			report.ExecutionsDeferred = len(names) + len(envUpdates)
+			t.log.Warn("Freeze window active, deferring redeploys and post-sync executions of %d changes.", report.ExecutionsDeferred)
+		}
+		return nil, nil
+	}
+	if len(t.deferredNames)+len(t.deferredEnvs) == 0 {
+		return names, envUpdates
+	}
+
+	t.log.Info("Freeze window ended, catching up on redeploys and post-sync executions of %d deferred changes.", len(t.deferredNames)+len(t.deferredEnvs))
+	seen := make(map[string]bool)
+	var merged []string
+	for _, name := range append(t.deferredNames, names...) {
+		if !seen[name] {
+			seen[name] = true
+			merged = append(merged, name)
+		}
+	}
+	envs := append(t.deferredEnvs, envUpdates...)
+	t.deferredNames, t.deferredEnvs = nil, nil
+	return merged, envs
+}
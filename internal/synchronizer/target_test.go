@@ -0,0 +1,78 @@
+package synchronizer
+
+import (
+	"reflect"
+	"testing"
+
+	"komodo-op/internal/config"
+	"komodo-op/internal/komodoclient"
+	"komodo-op/internal/logging"
+)
+
+func TestDeferExecutions(t *testing.T) {
+	s := &Synchronizer{cfg: &config.Config{RedeployMode: config.RedeployDeploy}}
+	target := &targetSync{Synchronizer: s, log: logging.With()}
+	stack := envUpdate{target: envTarget{kind: komodoclient.ResourceStack, name: "app"}}
+
+	// A frozen run keeps its changes
+	s.frozen = true
+	report := &TargetReport{ChangedVariables: []string{"A", "B"}}
+	names, envs := target.deferExecutions(report, []envUpdate{stack})
+	if names != nil || envs != nil {
+		t.Fatalf("frozen run returned %v, %v, want nothing", names, envs)
+	}
+	if report.ExecutionsDeferred != 3 {
+		t.Errorf("ExecutionsDeferred = %d, want 3", report.ExecutionsDeferred)
+	}
+
+	// The first run after the window gets them along with its own, without duplicates
+	s.frozen = false
+	names, envs = target.deferExecutions(&TargetReport{ChangedVariables: []string{"B", "C"}}, nil)
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if want := []envUpdate{stack}; !reflect.DeepEqual(envs, want) {
+		t.Errorf("envs = %v, want %v", envs, want)
+	}
+
+	// And the next one only its own
+	names, envs = target.deferExecutions(&TargetReport{ChangedVariables: []string{"D"}}, nil)
+	if want := []string{"D"}; !reflect.DeepEqual(names, want) || envs != nil {
+		t.Errorf("names, envs = %v, %v, want %v, nil", names, envs, want)
+	}
+}
+
+func TestDeferExecutionsWithoutExecutions(t *testing.T) {
+	s := &Synchronizer{cfg: &config.Config{RedeployMode: config.RedeployOff}, frozen: true}
+	target := &targetSync{Synchronizer: s, log: logging.With()}
+
+	report := &TargetReport{ChangedVariables: []string{"A"}}
+	target.deferExecutions(report, nil)
+	if report.ExecutionsDeferred != 0 || target.deferredNames != nil {
+		t.Errorf("deferred %d changes with nothing to execute", report.ExecutionsDeferred)
+	}
+}
+
+func TestMaxDeletions(t *testing.T) {
+	for _, limit := range []int{2, 3} {
+		komodo, client := newFakeKomodo(t)
+		for _, name := range []string{"OP__A", "OP__B", "OP__C"} {
+			komodo.variables[name] = komodoclient.VariableResponse{Name: name, Description: managedByMarker + " item"}
+		}
+		komodo.variables["OTHER"] = komodoclient.VariableResponse{Name: "OTHER"}
+		target := newTestTarget(&config.Config{}, client)
+		target.target.MaxDeletions = limit
+
+		report := target.run(nil, nil)
+		if limit == 2 {
+			// More orphans than the limit, none is deleted
+			if report.DeletionsBlocked != 3 || report.Deleted != 0 || report.Errors != 1 || len(komodo.variables) != 4 {
+				t.Errorf("limit %d: report = %+v with %d variables left, want 3 deletions blocked", limit, report, len(komodo.variables))
+			}
+			continue
+		}
+		if report.DeletionsBlocked != 0 || report.Deleted != 3 || report.Errors != 0 || len(komodo.variables) != 1 {
+			t.Errorf("limit %d: report = %+v with %d variables left, want 3 deleted", limit, report, len(komodo.variables))
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"komodo-op/internal/logging"
+	"komodo-op/internal/util"
+)
+
+// OTLP span kinds.
+const (
+	kindInternal = 1
+	kindClient   = 3
+)
+
+// OTLP status code of a failed span.
+const statusError = 2
+
+// exportQueueSize is the number of finished traces waiting for export beyond
+// which further traces are dropped, e.g. while the collector is unreachable.
+const exportQueueSize = 16
+
+// exporter sends finished traces to an OTLP/HTTP collector in the background,
+// so that a slow collector never delays runs.
+type exporter struct {
+	url         string
+	serviceName string
+	headers     map[string]string
+	httpClient  *http.Client
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan []*Span
+	done   chan struct{} // Closed once the queue is drained after close
+}
+
+var (
+	mu      sync.RWMutex
+	current *exporter // nil while tracing is disabled
+)
+
+// Configure enables tracing, exporting every finished trace to the OTLP/HTTP
+// traces endpoint at url. An empty url disables tracing. Traces queued by a
+// previous configuration are still exported.
+func Configure(url, serviceName string, headers map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if current != nil {
+		current.close()
+		current = nil
+	}
+	if url == "" {
+		return
+	}
+	current = &exporter{
+		url:         url,
+		serviceName: serviceName,
+		headers:     headers,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan []*Span, exportQueueSize),
+		done:        make(chan struct{}),
+	}
+	go current.loop()
+}
+
+// Shutdown disables tracing and waits up to timeout for the queued traces to be
+// exported. Returns false if some were not in time.
+func Shutdown(timeout time.Duration) bool {
+	mu.Lock()
+	exp := current
+	current = nil
+	mu.Unlock()
+	if exp == nil {
+		return true
+	}
+	exp.close()
+	select {
+	case <-exp.done:
+		return true
+	case <-time.After(timeout):
+		logging.Warn("Timed out exporting the remaining traces to %s", exp.url)
+		return false
+	}
+}
+
+// loop exports queued traces until the queue is closed and drained.
+func (e *exporter) loop() {
+	defer close(e.done)
+	for spans := range e.queue {
+		traceID := spans[0].trace.id
+		if err := e.export(spans); err != nil {
+			logging.Warn("Failed to export trace %s: %v", traceID, err)
+			continue
+		}
+		logging.Debug("Exported trace %s with %d spans", traceID, len(spans))
+	}
+}
+
+// enqueue queues a finished trace for export, dropping it if the queue is full.
+func (e *exporter) enqueue(spans []*Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	select {
+	case e.queue <- spans:
+	default:
+		logging.Warn("Trace export queue is full, dropping trace %s", spans[0].trace.id)
+	}
+}
+
+// close stops accepting traces; those queued are still exported.
+func (e *exporter) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+}
+
+// trace collects the finished spans of one trace until its root span ends.
+type trace struct {
+	id       string
+	exporter *exporter
+	mu       sync.Mutex
+	spans    []*Span
+}
+
+// Span is a timed operation within a trace. All methods are no-ops on a nil
+// Span, which is what StartTrace returns while tracing is disabled.
+//
+// Attributes are plain strings set by this tool: names, IDs and endpoints,
+// never secret values.
+type Span struct {
+	trace    *trace
+	id       string
+	parentID string
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    []string // Key-value pairs
+	err      string
+}
+
+// StartTrace starts the root span of a new trace, or returns nil if tracing is disabled.
+func StartTrace(name string, attrs ...string) *Span {
+	mu.RLock()
+	exp := current
+	mu.RUnlock()
+	if exp == nil {
+		return nil
+	}
+	t := &trace{id: newID(16), exporter: exp}
+	return newSpan(t, "", name, kindInternal, attrs)
+}
+
+func newSpan(t *trace, parentID, name string, kind int, attrs []string) *Span {
+	return &Span{
+		trace:    t,
+		id:       newID(8),
+		parentID: parentID,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		attrs:    attrs,
+	}
+}
+
+// Start starts a child span of s.
+func (s *Span) Start(name string, attrs ...string) *Span {
+	if s == nil {
+		return nil
+	}
+	return newSpan(s.trace, s.id, name, kindInternal, attrs)
+}
+
+// StartClient starts a child span of s for a request to a remote API.
+func (s *Span) StartClient(name string, attrs ...string) *Span {
+	if s == nil {
+		return nil
+	}
+	return newSpan(s.trace, s.id, name, kindClient, attrs)
+}
+
+// TraceID returns the hex ID of the trace s belongs to, empty if s is nil.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.trace.id
+}
+
+// SetAttributes adds key-value pairs to s.
+func (s *Span) SetAttributes(attrs ...string) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetError marks s as failed. The message is redacted like log output.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = logging.Redact(err.Error())
+}
+
+// End finishes s. Ending the root span queues the whole trace for export.
+func (s *Span) End() {
+	if s == nil || !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+
+	s.trace.mu.Lock()
+	s.trace.spans = append(s.trace.spans, s)
+	spans := s.trace.spans
+	s.trace.mu.Unlock()
+
+	if s.parentID == "" {
+		s.trace.exporter.enqueue(spans)
+	}
+}
+
+// --- OTLP/HTTP JSON encoding ---
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func toAttributes(pairs []string) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		attrs = append(attrs, otlpAttribute{Key: pairs[i], Value: otlpValue{StringValue: pairs[i+1]}})
+	}
+	return attrs
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// export sends spans to the collector in the OTLP/HTTP JSON encoding.
+func (e *exporter) export(spans []*Span) error {
+	scope := otlpScopeSpans{}
+	scope.Scope.Name = "komodo-op"
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.trace.id,
+			SpanID:            s.id,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: unixNano(s.start),
+			EndTimeUnixNano:   unixNano(s.end),
+			Attributes:        toAttributes(s.attrs),
+		}
+		if s.err != "" {
+			span.Status = otlpStatus{Code: statusError, Message: s.err}
+		}
+		scope.Spans = append(scope.Spans, span)
+	}
+
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = toAttributes([]string{"service.name", e.serviceName})
+	payload, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{resource}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", e.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send spans to %s: %w", e.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := util.ReadAll(resp.Body)
+		return fmt.Errorf("OTLP collector %s returned status %s: %s", e.url, resp.Status, string(body))
+	}
+	return nil
+}
+
+// newID returns a random hex ID of n bytes.
+func newID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// Zero IDs are invalid in OTLP, fall back to the clock
+		return fmt.Sprintf("%0*x", 2*n, time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
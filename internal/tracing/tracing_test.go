@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is an OTLP/HTTP endpoint recording the spans it receives, blocked
+// until release is closed.
+type collector struct {
+	mu      sync.Mutex
+	traces  int
+	spans   []otlpSpan
+	release chan struct{}
+}
+
+func newCollector(t *testing.T) (*collector, string) {
+	t.Helper()
+	c := &collector{release: make(chan struct{})}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-c.release
+		var request otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.traces++
+		for _, resource := range request.ResourceSpans {
+			for _, scope := range resource.ScopeSpans {
+				c.spans = append(c.spans, scope.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { Configure("", "", nil) })
+	return c, server.URL
+}
+
+func TestEndDoesNotWaitForExport(t *testing.T) {
+	c, url := newCollector(t)
+	Configure(url, "test", nil)
+
+	root := StartTrace("sync run", "run_id", "r1")
+	child := root.StartClient("komodo /read")
+	child.End()
+	start := time.Now()
+	root.End()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("End() took %v while the collector was blocked", elapsed)
+	}
+
+	close(c.release)
+	if !Shutdown(5 * time.Second) {
+		t.Fatal("Shutdown() timed out")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.traces != 1 || len(c.spans) != 2 {
+		t.Fatalf("collector received %d traces with %d spans, want 1 with 2", c.traces, len(c.spans))
+	}
+	if c.spans[1].ParentSpanID != "" || c.spans[0].ParentSpanID != c.spans[1].SpanID {
+		t.Errorf("spans = %+v, want the child then its root", c.spans)
+	}
+}
+
+func TestQueueIsBounded(t *testing.T) {
+	c, url := newCollector(t)
+	Configure(url, "test", nil)
+
+	// One trace is being exported while the queue fills up, the rest is dropped
+	for i := 0; i < exportQueueSize+5; i++ {
+		StartTrace("sync run").End()
+	}
+	close(c.release)
+	if !Shutdown(5 * time.Second) {
+		t.Fatal("Shutdown() timed out")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.traces > exportQueueSize+1 || c.traces < exportQueueSize {
+		t.Errorf("collector received %d traces, want at most %d", c.traces, exportQueueSize+1)
+	}
+}
+
+func TestShutdownTimesOut(t *testing.T) {
+	c, url := newCollector(t)
+	defer close(c.release)
+	Configure(url, "test", nil)
+
+	StartTrace("sync run").End()
+	if Shutdown(50 * time.Millisecond) {
+		t.Error("Shutdown() reported the trace exported while the collector was blocked")
+	}
+	if StartTrace("sync run") != nil {
+		t.Error("tracing still enabled after Shutdown()")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	Configure("", "", nil)
+	span := StartTrace("sync run")
+	if span != nil {
+		t.Fatalf("StartTrace() = %v while disabled, want nil", span)
+	}
+	// Every method is a no-op on the nil span
+	span.Start("child").End()
+	span.SetAttributes("k", "v")
+	span.End()
+	if span.TraceID() != "" {
+		t.Error("TraceID() of nil span is not empty")
+	}
+	if !Shutdown(time.Second) {
+		t.Error("Shutdown() without tracing timed out")
+	}
+}